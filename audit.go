@@ -0,0 +1,79 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// AuditRecord is a tamper-evident record of a request, passed to Config.AuditHook.
+//
+// The canonical form is built from newline-separated parts:
+//
+//	METHOD
+//	/request/path?query (the query only for requests with one)
+//	header-name:value   (one line per header, lowercased names, sorted, Authorization excluded)
+//	hex(SHA-256(body))
+//
+// so query parameters, such as ListChallenges' cursor, are covered too.
+// Signature is the hex-encoded HMAC-SHA256 of Canonical keyed with the secret,
+// as derived by Config.KeyDeriver when set.
+type AuditRecord struct {
+	Method    string
+	Path      string // With the raw query appended after "?", if any
+	Canonical string
+	Signature string
+}
+
+// auditRecord builds the signed canonical form of req
+func (c *KeyClaimClient) auditRecord(req *http.Request) (AuditRecord, error) {
+	var body []byte
+	if req.GetBody != nil {
+		reader, err := req.GetBody()
+		if err != nil {
+			return AuditRecord{}, fmt.Errorf("failed to read request body: %w", err)
+		}
+		body, err = io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return AuditRecord{}, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if strings.EqualFold(name, "Authorization") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	path := req.URL.Path
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+
+	lines := []string{req.Method, path}
+	for _, name := range names {
+		lines = append(lines, strings.ToLower(name)+":"+strings.Join(req.Header.Values(name), ","))
+	}
+	bodyHash := sha256.Sum256(body)
+	lines = append(lines, hex.EncodeToString(bodyHash[:]))
+	canonical := strings.Join(lines, "\n")
+
+	h := hmac.New(sha256.New, c.key)
+	h.Write([]byte(canonical))
+
+	return AuditRecord{
+		Method:    req.Method,
+		Path:      path,
+		Canonical: canonical,
+		Signature: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
@@ -0,0 +1,87 @@
+package keyclaim
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	var records []AuditRecord
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret: "test-secret",
+		AuditHook: func(record AuditRecord) {
+			records = append(records, record)
+		},
+	})
+	client.baseURL = server.URL
+
+	client.CreateChallenge(30)
+	client.CreateChallenge(30)
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d", len(records))
+	}
+	if records[0] != records[1] {
+		t.Errorf("Expected identical requests to produce identical records, got %+v and %+v", records[0], records[1])
+	}
+
+	bodyHash := sha256.Sum256([]byte(`{"ttl":30}`))
+	expected := "POST\n/api/challenge/create\naccept:application/json\ncontent-type:application/json\n" + hex.EncodeToString(bodyHash[:])
+	if records[0].Canonical != expected {
+		t.Errorf("Expected canonical %q, got %q", expected, records[0].Canonical)
+	}
+
+	h := hmac.New(sha256.New, []byte("test-secret"))
+	h.Write([]byte(expected))
+	if records[0].Signature != hex.EncodeToString(h.Sum(nil)) {
+		t.Errorf("Unexpected signature %s", records[0].Signature)
+	}
+}
+
+func TestAuditHook_Query(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	var records []AuditRecord
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret: "test-secret",
+		AuditHook: func(record AuditRecord) {
+			records = append(records, record)
+		},
+	})
+	client.baseURL = server.URL
+
+	for _, path := range []string{"/api/x?id=1", "/api/x?id=2"} {
+		if err := client.Do(context.Background(), "GET", path, nil, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d", len(records))
+	}
+	if records[0].Path != "/api/x?id=1" || !strings.HasPrefix(records[0].Canonical, "GET\n/api/x?id=1\n") {
+		t.Errorf("Expected the query in the record, got %+v", records[0])
+	}
+	if records[0].Signature == records[1].Signature {
+		t.Error("Expected requests differing only in their query to be signed differently")
+	}
+}
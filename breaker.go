@@ -0,0 +1,119 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the API while the circuit
+// breaker configured with Config.CircuitBreaker is open
+var ErrCircuitOpen = errors.New("keyclaim: circuit breaker is open")
+
+const defaultCircuitCooldown = 30 * time.Second
+
+// CircuitBreakerConfig configures the client's circuit breaker. After
+// FailureThreshold consecutive failed calls the breaker opens, and calls fail
+// with ErrCircuitOpen until Cooldown has passed. A single trial call is then
+// let through: if it succeeds the breaker closes, otherwise it opens again.
+//
+// Transport errors and 5xx responses count as failures. Other responses,
+// including 4xx and invalid validation results, mean the backend is healthy.
+// Calls that fail before reaching the backend, e.g. with ErrConcurrencyLimit
+// or an AuditHook error, count as neither.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // Defaults to 5
+	Cooldown         time.Duration // Defaults to 30 seconds
+}
+
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	trial    bool // A half-open trial call is in flight
+}
+
+func newCircuitBreaker(config *CircuitBreakerConfig) *circuitBreaker {
+	if config == nil {
+		return nil
+	}
+
+	b := &circuitBreaker{
+		threshold: config.FailureThreshold,
+		cooldown:  config.Cooldown,
+		now:       time.Now,
+	}
+	if b.threshold <= 0 {
+		b.threshold = 5
+	}
+	if b.cooldown <= 0 {
+		b.cooldown = defaultCircuitCooldown
+	}
+	return b
+}
+
+// allow reports whether a call may proceed, returning ErrCircuitOpen while
+// the breaker is open or a half-open trial call is already in flight. The
+// first result reports whether the call is the half-open trial, to be passed
+// on to record or release when it finishes.
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return false, nil
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.cooldown {
+		return false, ErrCircuitOpen
+	}
+
+	b.trial = true
+	return true, nil
+}
+
+// record updates the breaker with the outcome of a call let through by allow
+// that reached the backend. Calls cancelled by the caller say nothing about
+// the backend's health and leave the breaker unchanged.
+func (b *circuitBreaker) record(ctx context.Context, trial bool, resp *http.Response, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if trial {
+		b.trial = false
+	}
+
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	if err == nil && resp.StatusCode < http.StatusInternalServerError {
+		b.failures = 0
+		b.open = false
+		return
+	}
+
+	b.failures++
+	if trial || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = b.now()
+	}
+}
+
+// release ends a call let through by allow that never reached the backend,
+// leaving the breaker's state as it was
+func (b *circuitBreaker) release(trial bool) {
+	if !trial {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
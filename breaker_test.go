@@ -0,0 +1,153 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute},
+	})
+	client.baseURL = server.URL
+
+	now := time.Now()
+	client.breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateChallenge(30); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected a server error on call %d, got %v", i+1, err)
+		}
+	}
+
+	// Open: calls are short-circuited without reaching the server
+	if _, err := client.CreateChallenge(30); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+	if _, err := client.ValidateChallenge("test-challenge", "test-response", nil); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen from validate, got %v", err)
+	}
+	if got := requests.Load(); got != 3 {
+		t.Errorf("Expected 3 requests to reach the server, got %d", got)
+	}
+
+	// Half-open: a failing trial call opens the breaker again
+	now = now.Add(time.Minute)
+	if _, err := client.CreateChallenge(30); err == nil || errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected the trial call to reach the server, got %v", err)
+	}
+	if _, err := client.CreateChallenge(30); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after a failed trial, got %v", err)
+	}
+
+	// Half-open: a successful trial call closes the breaker
+	failing.Store(false)
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if _, err := client.CreateChallenge(30); err != nil {
+			t.Fatalf("Expected recovery after the cooldown, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreaker_ClientErrorsDontTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_api_key"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 1},
+	})
+	client.baseURL = server.URL
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateChallenge(30); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected 4xx responses not to open the breaker, got %v", err)
+		}
+	}
+}
+
+func TestCircuitBreaker_LocalErrorsDontTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:                "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CircuitBreaker:        &CircuitBreakerConfig{FailureThreshold: 1},
+		MaxConcurrency:        1,
+		FailFastOnConcurrency: true,
+	})
+	client.baseURL = server.URL
+
+	client.slots <- struct{}{} // Occupy the only slot
+	for i := 0; i < 3; i++ {
+		if _, err := client.CreateChallenge(30); !errors.Is(err, ErrConcurrencyLimit) {
+			t.Fatalf("Expected ErrConcurrencyLimit, got %v", err)
+		}
+	}
+	<-client.slots
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Errorf("Expected the concurrency limit not to open the breaker, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_TrialOwnership(t *testing.T) {
+	breaker := newCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// A call let through before the breaker opened
+	early, _ := breaker.allow()
+	breaker.record(ctx, false, nil, errors.New("connection refused"))
+
+	now = now.Add(time.Minute)
+	trial, err := breaker.allow()
+	if !trial || err != nil {
+		t.Fatalf("Expected a trial call after the cooldown, got (%v, %v)", trial, err)
+	}
+
+	// The early call finishing mustn't free the trial slot
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	breaker.record(cancelled, early, nil, context.Canceled)
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen while the trial is in flight, got %v", err)
+	}
+
+	// A trial refused locally frees the slot without counting
+	breaker.release(trial)
+	if trial, err := breaker.allow(); !trial || err != nil {
+		t.Errorf("Expected a new trial after the first was released, got (%v, %v)", trial, err)
+	}
+}
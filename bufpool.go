@@ -0,0 +1,101 @@
+package keyclaim
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// requestBody is a pooled buffer holding a marshaled request body. The
+// transport may still be writing a body after Do has returned, so the buffer
+// is reference counted: KeyClaimClient.do holds one reference for the whole
+// call and every reader handed out holds one until it is closed. The buffer
+// returns to the pool when the last reference is dropped. Bodies of requests
+// that are never sent are simply left to the garbage collector.
+//
+// Readers are never pooled with the buffer: a transport may close a reader
+// late and more than once, and a reused reader would let such a Close drop a
+// reference taken by the buffer's next owner.
+type requestBody struct {
+	buf     bytes.Buffer
+	enc     *json.Encoder
+	data    []byte
+	getBody func() (io.ReadCloser, error)
+	refs    atomic.Int32
+}
+
+const maxPooledBodySize = 64 << 10
+
+var requestBodyPool = sync.Pool{
+	New: func() any {
+		b := &requestBody{}
+		b.enc = json.NewEncoder(&b.buf)
+		b.getBody = b.open
+		return b
+	},
+}
+
+// marshalRequestBody encodes v into a pooled buffer. The returned body holds
+// the reference for the caller, to be dropped with release; set the final
+// bytes with setData before opening readers.
+func marshalRequestBody(v interface{}) (*requestBody, []byte, error) {
+	b := requestBodyPool.Get().(*requestBody)
+	b.buf.Reset()
+	if err := b.enc.Encode(v); err != nil {
+		requestBodyPool.Put(b)
+		return nil, nil, err
+	}
+	b.refs.Store(1)
+
+	// Encode terminates the value with a newline, which json.Marshal doesn't
+	data := b.buf.Bytes()
+	return b, data[:len(data)-1], nil
+}
+
+// setData sets the bytes sent as the body, which may be a modified copy of
+// the marshaled ones
+func (b *requestBody) setData(data []byte) {
+	b.data = data
+}
+
+// reader returns the body for Request.Body, taking a reference
+func (b *requestBody) reader() io.ReadCloser {
+	r, _ := b.open()
+	return r
+}
+
+// open returns a fresh reader for Request.GetBody, taking a reference
+func (b *requestBody) open() (io.ReadCloser, error) {
+	b.refs.Add(1)
+	r := &bodyReader{owner: b}
+	r.Reset(b.data)
+	return r, nil
+}
+
+// release drops a reference, returning the buffer to the pool with the last.
+// Unusually large buffers are dropped instead, so one big request doesn't pin
+// its memory.
+func (b *requestBody) release() {
+	if b.refs.Add(-1) == 0 {
+		b.data = nil
+		if b.buf.Cap() <= maxPooledBodySize {
+			requestBodyPool.Put(b)
+		}
+	}
+}
+
+// bodyReader reads a requestBody, dropping its reference on the first Close
+type bodyReader struct {
+	bytes.Reader
+	owner  *requestBody
+	closed atomic.Bool
+}
+
+func (r *bodyReader) Close() error {
+	if r.closed.CompareAndSwap(false, true) {
+		r.owner.release()
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRequestBodyPool_Concurrent(t *testing.T) {
+	var attempts sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if int64(len(body)) != r.ContentLength {
+			t.Errorf("Expected Content-Length %d, got %d", len(body), r.ContentLength)
+		}
+		var request struct {
+			TTL int `json:"ttl"`
+		}
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("Expected a JSON body, got %q", body)
+		}
+
+		// Fail every first attempt so retries rewind the pooled body
+		counter, _ := attempts.LoadOrStore(request.TTL, new(atomic.Int32))
+		if counter.(*atomic.Int32).Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge", ExpiresIn: request.TTL})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxRetries:   1,
+		RetryBackoff: 1,
+	})
+	client.baseURL = server.URL
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		wg.Add(1)
+		go func(ttl int) {
+			defer wg.Done()
+			challenge, err := client.CreateChallenge(ttl)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			if challenge.ExpiresIn != ttl {
+				t.Errorf("Expected the server to receive TTL %d, got %d", ttl, challenge.ExpiresIn)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRequestBody_LateClose(t *testing.T) {
+	b, data, err := marshalRequestBody(map[string]int{"ttl": 30})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	b.setData(data)
+
+	// The first request's transport closes its body, then the call ends
+	stale := b.reader()
+	stale.Close()
+	b.release()
+
+	// The buffer is reused for a second request, and a duplicate Close from
+	// the first transport arrives late
+	b.refs.Store(1)
+	b.setData(data)
+	current := b.reader()
+	stale.Close()
+
+	if refs := b.refs.Load(); refs != 2 {
+		t.Errorf("Expected the late Close not to drop the new owner's reference, got %d refs", refs)
+	}
+	if body, _ := io.ReadAll(current); string(body) != `{"ttl":30}` {
+		t.Errorf("Expected the current body to be intact, got %q", body)
+	}
+}
+
+func BenchmarkNewRequest(b *testing.B) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	opts := ValidateChallengeOptions{Challenge: "test-challenge", Response: "115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		req, err := client.newRequest(context.Background(), "POST", "/api/challenge/validate", opts, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		req.Body.Close()
+		if body, ok := req.Body.(*bodyReader); ok {
+			body.owner.release()
+		}
+	}
+}
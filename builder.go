@@ -0,0 +1,94 @@
+package keyclaim
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ResponseBuilder builds a response with any combination of nonce, timestamp
+// and custom data in one chain. Obtain one with KeyClaimClient.NewResponse.
+// A builder is not safe for concurrent use.
+type ResponseBuilder struct {
+	client     *KeyClaimClient
+	challenge  string
+	method     ResponseMethod
+	customData interface{}
+	nonce      bool
+	timestamp  *time.Time
+}
+
+// BuildMeta holds the values a built response depends on besides the
+// challenge, which must be sent along with it
+type BuildMeta struct {
+	Method    ResponseMethod
+	Nonce     string // Empty unless WithNonce was used
+	Timestamp string // Unix seconds, empty unless WithTimestamp was used
+}
+
+// NewResponse starts building a response to challenge. The method defaults to
+// ResponseMethodHMAC.
+func (c *KeyClaimClient) NewResponse(challenge string) *ResponseBuilder {
+	return &ResponseBuilder{client: c, challenge: challenge, method: ResponseMethodHMAC}
+}
+
+// Method sets the response method
+func (b *ResponseBuilder) Method(method ResponseMethod) *ResponseBuilder {
+	b.method = method
+	return b
+}
+
+// WithNonce mixes a fresh random nonce into the pre-image, as
+// GenerateResponseWithNonce does
+func (b *ResponseBuilder) WithNonce() *ResponseBuilder {
+	b.nonce = true
+	return b
+}
+
+// WithTimestamp binds the response to t, as GenerateResponseAt does. It
+// requires the hmac or hash method.
+func (b *ResponseBuilder) WithTimestamp(t time.Time) *ResponseBuilder {
+	b.timestamp = &t
+	return b
+}
+
+// WithCustomData sets the custom data for ResponseMethodCustom
+func (b *ResponseBuilder) WithCustomData(customData interface{}) *ResponseBuilder {
+	b.customData = customData
+	return b
+}
+
+// Build generates the response. The pre-image extends the challenge with each
+// option in a fixed order, so that
+//
+//	challenge + ":" + nonce + ":" + timestamp
+//
+// replaces the challenge before the method is applied when both are used.
+// Without a nonce the result is deterministic.
+func (b *ResponseBuilder) Build() (string, BuildMeta, error) {
+	meta := BuildMeta{Method: b.method}
+	challenge := b.challenge
+
+	if b.nonce {
+		nonce, err := generateNonce()
+		if err != nil {
+			return "", BuildMeta{}, err
+		}
+		meta.Nonce = nonce
+		challenge += ":" + nonce
+	}
+
+	if b.timestamp != nil {
+		if b.method != ResponseMethodHMAC && b.method != ResponseMethodHash {
+			return "", BuildMeta{}, fmt.Errorf("timestamped responses require the hmac or hash method, got %q", b.method)
+		}
+		meta.Timestamp = strconv.FormatInt(b.timestamp.Unix(), 10)
+		challenge += ":" + meta.Timestamp
+	}
+
+	response, err := b.client.GenerateResponse(challenge, b.method, b.customData)
+	if err != nil {
+		return "", BuildMeta{}, err
+	}
+	return response, meta, nil
+}
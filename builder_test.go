@@ -0,0 +1,87 @@
+package keyclaim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResponseBuilder(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	ts := time.Unix(1793491200, 0)
+
+	t.Run("default", func(t *testing.T) {
+		response, meta, err := client.NewResponse("test-challenge").Build()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response != "115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42" {
+			t.Errorf("Expected the hmac test vector, got %s", response)
+		}
+		if meta != (BuildMeta{Method: ResponseMethodHMAC}) {
+			t.Errorf("Unexpected meta %+v", meta)
+		}
+	})
+
+	t.Run("deterministic", func(t *testing.T) {
+		build := func() string {
+			response, _, err := client.NewResponse("test-challenge").
+				Method(ResponseMethodHash).
+				WithTimestamp(ts).
+				Build()
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			return response
+		}
+		if first, second := build(), build(); first != second {
+			t.Errorf("Expected identical responses, got %s and %s", first, second)
+		}
+
+		expected, _, _ := client.GenerateResponseAt("test-challenge", ResponseMethodHash, ts)
+		if build() != expected {
+			t.Error("Expected the same response as GenerateResponseAt")
+		}
+	})
+
+	t.Run("custom data", func(t *testing.T) {
+		response, _, err := client.NewResponse("test-challenge").
+			Method(ResponseMethodCustom).
+			WithCustomData("custom-string").
+			Build()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if response != "49b3888d279dd435d7cafaacc81851c6ed224052e898a6e66435534e666c0bc7" {
+			t.Errorf("Expected the custom test vector, got %s", response)
+		}
+	})
+
+	t.Run("nonce and timestamp", func(t *testing.T) {
+		response, meta, err := client.NewResponse("test-challenge").
+			WithNonce().
+			WithTimestamp(ts).
+			Build()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(meta.Nonce) != 32 || meta.Timestamp != "1793491200" {
+			t.Errorf("Unexpected meta %+v", meta)
+		}
+
+		expected, _ := client.GenerateResponse("test-challenge:"+meta.Nonce+":"+meta.Timestamp, ResponseMethodHMAC, nil)
+		if response != expected {
+			t.Errorf("Expected response %s for the documented pre-image, got %s", expected, response)
+		}
+
+		second, secondMeta, _ := client.NewResponse("test-challenge").WithNonce().WithTimestamp(ts).Build()
+		if secondMeta.Nonce == meta.Nonce || second == response {
+			t.Error("Expected a fresh nonce per build")
+		}
+	})
+
+	t.Run("timestamp requires hmac or hash", func(t *testing.T) {
+		if _, _, err := client.NewResponse("test-challenge").Method(ResponseMethodEcho).WithTimestamp(ts).Build(); err == nil {
+			t.Error("Expected error for a timestamped echo response")
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrNotSupported is returned when the server doesn't implement an optional
+// endpoint
+var ErrNotSupported = errors.New("keyclaim: not supported by the server")
+
+// CancelChallenge invalidates an unused challenge, freeing the server-side
+// state and quota it holds. It is meant for challenges created speculatively,
+// such as surplus challenges from a ChallengePool. Servers without the cancel
+// endpoint yield an error matching ErrNotSupported.
+func (c *KeyClaimClient) CancelChallenge(challenge string) error {
+	return c.CancelChallengeContext(context.Background(), challenge)
+}
+
+// CancelChallengeContext invalidates a challenge like CancelChallenge,
+// honoring ctx cancellation
+func (c *KeyClaimClient) CancelChallengeContext(ctx context.Context, challenge string) error {
+	reqBody := map[string]interface{}{
+		"challenge": challenge,
+	}
+
+	req, err := c.newRequest(ctx, "POST", "/api/challenge/cancel", reqBody, false)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to cancel challenge: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: challenge cancellation", ErrNotSupported)
+	default:
+		return c.handleErrorResponse(resp, "Failed to cancel challenge")
+	}
+}
@@ -0,0 +1,47 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancelChallenge(t *testing.T) {
+	var canceled string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/challenge/cancel" {
+			t.Errorf("Expected POST /api/challenge/cancel, got %s %s", r.Method, r.URL.Path)
+		}
+		var body struct {
+			Challenge string `json:"challenge"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		canceled = body.Challenge
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if err := client.CancelChallenge("test-challenge-123"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if canceled != "test-challenge-123" {
+		t.Errorf("Expected challenge test-challenge-123 to be canceled, got %q", canceled)
+	}
+}
+
+func TestCancelChallenge_NotSupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if err := client.CancelChallenge("test-challenge-123"); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const defaultCapabilitiesTTL = 5 * time.Minute
+
+// Capabilities describes the optional features supported by the server
+type Capabilities struct {
+	Batch      bool     `json:"batch"`
+	Encryption bool     `json:"encryption"`
+	SHA512     bool     `json:"sha512"`
+	Methods    []string `json:"methods,omitempty"` // Supported response methods, if advertised
+
+	// Discovered is false when the server has no capabilities endpoint, in
+	// which case every feature is reported as unsupported
+	Discovered bool `json:"-"`
+}
+
+// GetCapabilities queries the server's capabilities endpoint. The result is
+// cached on the client for Config.CapabilitiesTTL. Servers without the
+// endpoint (404) yield a Capabilities with Discovered set to false.
+func (c *KeyClaimClient) GetCapabilities() (*Capabilities, error) {
+	return c.GetCapabilitiesContext(context.Background())
+}
+
+// GetCapabilitiesContext queries the server's capabilities like
+// GetCapabilities, honoring ctx cancellation
+func (c *KeyClaimClient) GetCapabilitiesContext(ctx context.Context) (*Capabilities, error) {
+	c.capabilitiesMu.Lock()
+	defer c.capabilitiesMu.Unlock()
+
+	if c.capabilities != nil && time.Since(c.capabilitiesFetchedAt) < c.capabilitiesTTL {
+		return c.capabilities, nil
+	}
+
+	req, err := c.newRequest(ctx, "GET", "/api/capabilities", nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get capabilities: %w", err)
+	}
+	defer resp.Body.Close()
+
+	capabilities := &Capabilities{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		if err := checkContentType(resp); err != nil {
+			return nil, err
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", err)
+		}
+		if err := json.Unmarshal(renameFields(bodyBytes, c.responseFieldMap), capabilities); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		capabilities.Discovered = true
+	case http.StatusNotFound:
+		// Older servers don't advertise capabilities
+	default:
+		return nil, c.handleErrorResponse(resp, "Failed to get capabilities")
+	}
+
+	c.capabilities = capabilities
+	c.capabilitiesFetchedAt = time.Now()
+
+	return capabilities, nil
+}
@@ -0,0 +1,79 @@
+package keyclaim
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/capabilities" {
+			t.Errorf("Expected path /api/capabilities, got %s", r.URL.Path)
+		}
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"batch":true,"encryption":false,"sha512":true,"methods":["echo","hmac","hash","custom"]}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	capabilities, err := client.GetCapabilities()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !capabilities.Discovered || !capabilities.Batch || capabilities.Encryption || !capabilities.SHA512 {
+		t.Errorf("Unexpected capabilities %+v", capabilities)
+	}
+	if len(capabilities.Methods) != 4 {
+		t.Errorf("Expected 4 methods, got %v", capabilities.Methods)
+	}
+
+	if _, err := client.GetCapabilities(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected capabilities to be cached, got %d requests", requests)
+	}
+}
+
+func TestGetCapabilities_NotFound(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	capabilities, err := client.GetCapabilities()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if capabilities.Discovered || capabilities.Batch || capabilities.Encryption || capabilities.SHA512 {
+		t.Errorf("Expected everything unsupported, got %+v", capabilities)
+	}
+}
+
+func TestGetCapabilities_CacheExpiry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"batch":true}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CapabilitiesTTL: 1,
+	})
+	client.baseURL = server.URL
+
+	client.GetCapabilities()
+	client.GetCapabilities()
+	if requests != 2 {
+		t.Errorf("Expected cache to expire, got %d requests", requests)
+	}
+}
@@ -2,23 +2,47 @@ package keyclaim
 
 import (
 	"bytes"
-	"crypto/hmac"
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"maps"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	defaultBaseURLB64 = "aHR0cHM6Ly9rZXljbGFpbS5vcmc=" // https://keyclaim.org
 	defaultTimeout    = 30 * time.Second
 	defaultTTL        = 30
+	defaultBackoff    = 500 * time.Millisecond
+	defaultAccept     = "application/json"
 )
 
+// DefaultBaseURL overrides the built-in default base URL when non-empty. It is
+// meant to be injected at build time by white-labeled distributions:
+//
+//	go build -ldflags "-X github.com/creasoftlb/keyclaim-go-sdk.DefaultBaseURL=https://auth.example.com"
+var DefaultBaseURL string
+
 // ResponseMethod represents the method for generating a response
 type ResponseMethod string
 
@@ -29,18 +53,369 @@ const (
 	ResponseMethodCustom ResponseMethod = "custom"
 )
 
+// TTLUnit is the unit a TTL is sent to the server in
+type TTLUnit int
+
+const (
+	// TTLUnitSeconds sends TTLs in seconds, as the KeyClaim API expects
+	TTLUnitSeconds TTLUnit = iota
+	// TTLUnitMilliseconds sends TTLs in milliseconds, for servers that
+	// expect them
+	TTLUnitMilliseconds
+)
+
+// Doer sends an HTTP request and returns its response, as *http.Client does.
+// It is the client's only dependency on the HTTP layer, see Config.Doer.
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
 // Config holds the configuration for KeyClaimClient
 type Config struct {
 	APIKey string
 	Secret string // Optional, defaults to API key
+
+	// SecretBytes sets a binary secret, used as the HMAC key exactly as given
+	// (KeyDeriver isn't applied to it). Mutually exclusive with Secret.
+	SecretBytes []byte
+
+	// AdditionalSecrets are also accepted by VerifyResponse, on top of the
+	// primary secret, for use during secret rotation windows. Responses are
+	// always generated with the primary secret.
+	AdditionalSecrets []string
+
+	// HashAlgorithm selects the digest behind the hmac and hash response
+	// methods: HashSHA256 (the default), HashSHA512, HashBLAKE2b256 or
+	// HashSHA3_256. The server must be configured to match. The custom
+	// method always uses SHA-256.
+	HashAlgorithm HashAlgorithm
+
+	// KeyDeriver, when set, derives the actual HMAC key from the secret (and
+	// from each of AdditionalSecrets), e.g. with HKDF or PBKDF2. It is applied
+	// once at construction. Defaults to using the secret's bytes as-is.
+	KeyDeriver func(secret string) []byte
+
+	// WarnOnSharedSecret logs a warning at construction when the secret is
+	// the API key, whether by default or explicitly, since anyone holding the
+	// key can then forge responses. Defaults to true; only applies when
+	// Logger is set.
+	WarnOnSharedSecret *bool
+
+	// RequireExplicitSecret makes NewClientWithConfig fail with
+	// ErrSecretRequired when neither Secret nor SecretBytes is set, instead
+	// of falling back to the API key. Passing the API key explicitly as
+	// Secret is still accepted.
+	RequireExplicitSecret bool
+
+	// InsecureSkipVerify disables TLS certificate verification on the default
+	// transport.
+	//
+	// WARNING: this makes the connection vulnerable to man-in-the-middle
+	// attacks, which is exactly what KeyClaim is meant to protect against.
+	// Only use it against a local development instance with a self-signed
+	// certificate, never in production.
+	InsecureSkipVerify bool
+
+	// PinnedCertSHA256, when set, only accepts servers whose leaf
+	// certificate's SHA-256 fingerprint, hex-encoded, is in the list, on top
+	// of the regular verification. Colons and case are ignored, so the output
+	// of `openssl x509 -noout -fingerprint -sha256` can be used as-is.
+	PinnedCertSHA256 []string
+
+	// Doer, when set, sends the client's HTTP requests in place of the
+	// built-in *http.Client, e.g. a mock returning canned responses in tests,
+	// or a client with its own middleware. InsecureSkipVerify and
+	// PinnedCertSHA256 only configure the built-in client.
+	Doer Doer
+
+	// UnwrapData makes ValidateChallenge accept responses wrapped in a
+	// top-level "data" object, as some API gateways do:
+	// {"data": {"valid": true, ...}}. Unwrapping only happens when the
+	// standard top-level fields are absent.
+	UnwrapData bool
+
+	// AutoRefreshExpired makes Validate retry the full flow once, with a
+	// fresh challenge of the same TTL and the same method, when validation
+	// fails because the challenge expired.
+	AutoRefreshExpired bool
+
+	// ChallengeSource, when set, is used by Validate to obtain challenges
+	// instead of calling the create endpoint. This allows injecting
+	// challenges from a cache, a pool or a test stub into the full flow.
+	ChallengeSource func(ctx context.Context, ttl int) (*CreateChallengeResponse, error)
+
+	// Decryptor, when set, is called by Validate to decrypt encrypted
+	// challenges, e.g. through an external KMS. The response is generated
+	// over the decrypted challenge, which is also sent as
+	// decryptedChallenge. Without it, Validate returns ErrDecryptionRequired
+	// for encrypted challenges.
+	Decryptor func(ctx context.Context, encrypted string) (string, error)
+
+	// BaseURL overrides the API's base URL, e.g. for self-hosted deployments.
+	// Defaults to DefaultBaseURL when set, otherwise https://keyclaim.org. A
+	// trailing slash is ignored.
+	BaseURL string
+
+	// CreateBaseURL and ValidateBaseURL override BaseURL for the create and
+	// validate endpoints respectively, for deployments that split them across
+	// hosts, e.g. a regional create with a central validate. Each falls back
+	// to BaseURL when empty.
+	CreateBaseURL   string
+	ValidateBaseURL string
+
+	// FallbackBaseURLs are tried in order by ValidateChallenge when the base
+	// URL can't be reached or answers with a 5xx, for geo-redundant
+	// deployments. ResponseMeta.Endpoint reports which one answered.
+	FallbackBaseURLs []string
+
+	// RequireHTTPS rejects base URLs, including fallbacks, that don't use
+	// https, since the API key would be sent in cleartext. Defaults to true;
+	// set it to false for local development, which logs a warning when an
+	// http URL is used.
+	RequireHTTPS *bool
+
+	// MaxConcurrency bounds the number of simultaneous HTTP requests made by
+	// the client across all methods. Further requests wait for a free slot,
+	// honoring context cancellation. Defaults to 0 (unbounded).
+	MaxConcurrency int
+
+	// FailFastOnConcurrency makes requests fail with ErrConcurrencyLimit
+	// instead of waiting when all MaxConcurrency slots are in use.
+	FailFastOnConcurrency bool
+
+	// Accept is the Accept header sent with every request. Defaults to
+	// "application/json". Responses must still declare a JSON content type
+	// (application/json or a +json suffix).
+	Accept string
+
+	// MaxRetries is the number of times a request is retried after a
+	// transport error, a 429 or a 5xx response. Defaults to 0 (no retries).
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// subsequent attempt. Defaults to 500ms.
+	RetryBackoff time.Duration
+
+	// DebugHook, when set, receives the raw request and response bodies of
+	// every API call, with the Authorization header redacted. Intended for
+	// local troubleshooting only: bodies may contain challenges and responses.
+	DebugHook func(DebugExchange)
+
+	// ValidateChallengeEncoding makes GenerateResponse reject challenges that
+	// aren't valid UTF-8 or contain non-printable characters, which can
+	// confuse logging and custom data concatenation. Off by default.
+	ValidateChallengeEncoding bool
+
+	// EchoTransform, when set, is applied to the challenge by
+	// ResponseMethodEcho, for servers expecting a transformed echo (e.g.
+	// base64-encoded). Defaults to returning the challenge unchanged.
+	EchoTransform func(challenge string) string
+
+	// CustomDataValidator, when set, is called by GenerateResponse with the
+	// custom data before it is serialized for ResponseMethodCustom, so
+	// misconfigured payloads are caught early.
+	CustomDataValidator func(customData interface{}) error
+
+	// SignRequests attaches X-Timestamp and X-Signature headers to every
+	// authenticated request, for deployments that require signed requests
+	// to prevent replay. See signRequest for the canonicalization.
+	SignRequests bool
+
+	// BodyInterceptor, when set, is called with the path and the marshaled
+	// JSON body of every request that has one, after FieldMap is applied and
+	// before the request is built and signed, and may return a modified
+	// body, e.g. with an extra field. Returning an error aborts the request.
+	// The body passed in is a copy the interceptor may keep.
+	BodyInterceptor func(path string, body []byte) ([]byte, error)
+
+	// AuditHook, when set, receives a signed canonical form of every request
+	// before it is sent, for tamper-evident audit trails. See AuditRecord.
+	AuditHook func(AuditRecord)
+
+	// CapabilitiesTTL is how long GetCapabilities caches the server's
+	// capabilities. Defaults to 5 minutes.
+	CapabilitiesTTL time.Duration
+
+	// RequestIDFromContext extracts a correlation ID from the request context,
+	// sent as the X-Request-ID header and included in logs and errors.
+	// Defaults to the ID stored with WithRequestID.
+	RequestIDFromContext func(ctx context.Context) string
+
+	// Logger receives warnings about insecure or unusual configuration, a
+	// debug record per request, and a warning for requests that fail with a
+	// transport error or a 5xx. *slog.Logger implements it; other logging
+	// libraries need a small adapter. The API key and secret are redacted
+	// before records reach it. Optional, nothing is logged when nil.
+	Logger Logger
+
+	// StrictTTL makes a zero TTL an error (ErrZeroTTL) instead of selecting
+	// the 30 second default, to catch calls that forget to set one. Other
+	// out-of-range TTLs are left for the server to reject.
+	StrictTTL bool
+
+	// TTLUnit is the unit TTLs are sent in. The KeyClaim API expects seconds,
+	// the default; use TTLUnitMilliseconds for servers that expect
+	// milliseconds. TTLs passed as ints are always in seconds and converted.
+	TTLUnit TTLUnit
+
+	// StrictDecode makes create and validate responses carrying fields the
+	// SDK doesn't model an error (ErrUnknownField), to catch protocol drift
+	// during upgrades. Off by default so newer servers stay compatible.
+	StrictDecode bool
+
+	// MaxChallengeAge, when positive, makes Validate refuse challenges
+	// received longer ago than this with ErrStaleChallenge, even if the
+	// server's TTL hasn't expired, for policies stricter than the TTL.
+	// Challenges whose age can't be told, because ReceivedAt is unset (e.g.
+	// from a ChallengeSource that builds them itself), are refused too.
+	MaxChallengeAge time.Duration
+
+	// ErrorOnInvalid makes the validate methods return a
+	// *ValidationFailedError, matching ErrValidationFailed, along with the
+	// result when the server reports it invalid, for error-based control flow.
+	// Off by default, so invalid results come back with a nil error.
+	ErrorOnInvalid bool
+
+	// FieldMap renames JSON fields for servers that don't use the standard
+	// names, mapping the SDK's name to the server's, e.g.
+	// {"challenge": "token", "ttl": "ttl_seconds"}. It applies to the
+	// top-level fields of request bodies and, in reverse, of responses.
+	FieldMap map[string]string
+
+	// CircuitBreaker, when set, stops calls from reaching a failing backend
+	// after consecutive failures, failing them with ErrCircuitOpen until a
+	// cooldown has passed. See CircuitBreakerConfig.
+	CircuitBreaker *CircuitBreakerConfig
+
+	// FailureCache, when set, remembers recently failed challenges so that
+	// validating one again returns the cached outcome without an API call.
+	// See FailureCacheConfig.
+	FailureCache *FailureCacheConfig
+
+	// ReplayWindow, when positive, makes the client remember the challenge
+	// and response pairs the server answered for this long, and warn through
+	// Logger and an EventReplaySuspected event before submitting one again,
+	// to catch application bugs that resubmit. Pairs that failed with a
+	// transport error or a 5xx aren't remembered, so they can be retried.
+	// RejectReplays returns ErrReplayDetected instead of sending such a pair.
+	ReplayWindow  time.Duration
+	RejectReplays bool
+
+	// ValidationSuccessPredicate, when set, decides whether a validation
+	// result counts as a success in place of IsValid, e.g. to reject results
+	// with no remaining quota. It is applied by Succeeded, and thus by
+	// VerifyMiddleware and the full-flow Validate methods.
+	ValidationSuccessPredicate func(*ValidateChallengeResponse) bool
+
+	// ErrorClassifier, when set, sees the status code and body of every API
+	// response before the SDK interprets it. A non-nil error is returned to
+	// the caller as-is, which lets integrators map backend-specific responses
+	// to the SDK's sentinel errors, e.g. 409 to ErrQuotaExceeded. Returning
+	// nil leaves the response to the default interpretation.
+	ErrorClassifier func(statusCode int, body []byte) error
+
+	// StatusHandlers run custom logic on specific status codes, e.g. to
+	// trigger re-authentication on 401 or alert on 402. The handler for a
+	// response's status is called before ErrorClassifier and the default
+	// interpretation; a non-nil error is returned to the caller as-is, while
+	// nil continues as usual. The handler may read the body; it is restored
+	// afterwards.
+	StatusHandlers map[int]func(*http.Response) error
+
+	// HistorySize, when positive, keeps the last HistorySize request/response
+	// pairs in memory for support purposes. See KeyClaimClient.History.
+	HistorySize int
+
+	// TraceTimings records DNS, connect, TLS handshake and time-to-first-byte
+	// durations with net/http/httptrace, reported as ResponseMeta.Trace by
+	// the WithMeta methods, for latency debugging
+	TraceTimings bool
+
+	// DefaultCustomData is used by the custom method when a call passes nil
+	// custom data, for apps that always send the same data, such as an app
+	// identifier. Custom data passed to a call takes precedence.
+	DefaultCustomData interface{}
+
+	// SendCustomData makes Validate send the custom method's data in the
+	// validate request's custom_data field, in addition to hashing it into
+	// the response, for servers that recompute the response from it. Off by
+	// default, so the data never leaves the client.
+	SendCustomData bool
+
+	// IncludeKeyFingerprint sends KeyFingerprint in validate requests, as
+	// key_fingerprint, so that servers with several keys can select the
+	// secret to verify with. Off by default.
+	IncludeKeyFingerprint bool
+
+	// Events, when set, receives monitoring events such as challenge
+	// creations, validation outcomes and scheduled retries. Sends never
+	// block: events are dropped while the channel is full, so give it a
+	// buffer sized for the consumer.
+	Events chan<- Event
 }
 
 // KeyClaimClient is the main client for interacting with the KeyClaim API
 type KeyClaimClient struct {
-	apiKey  string
-	baseURL string
-	secret  string
-	client  *http.Client
+	apiKeyMu sync.RWMutex // Guards apiKey, which SetAPIKey can swap
+	apiKey   string
+	baseURL  string
+	client   *http.Client
+	doer     Doer // Sends requests, defaults to client
+	logger   Logger
+
+	accept                     string
+	maxRetries                 int
+	retryBackoff               time.Duration
+	unwrapData                 bool
+	signRequests               bool
+	bodyInterceptor            func(path string, body []byte) ([]byte, error)
+	challengeSource            func(ctx context.Context, ttl int) (*CreateChallengeResponse, error)
+	decryptor                  func(ctx context.Context, encrypted string) (string, error)
+	debugHook                  func(DebugExchange)
+	customDataValidator        func(interface{}) error
+	auditHook                  func(AuditRecord)
+	autoRefreshExpired         bool
+	key                        []byte
+	customSecret               bool // Whether the secret differs from the API key given at construction
+	additionalKeys             [][]byte
+	echoTransform              func(string) string
+	createBaseURL              string
+	validateBaseURL            string
+	fallbackBaseURLs           []string
+	validateChallengeEncoding  bool
+	requestIDFromContext       func(context.Context) string
+	slots                      chan struct{}
+	failFastOnConcurrency      bool
+	strictTTL                  bool
+	ttlUnit                    TTLUnit
+	strictDecode               bool
+	maxChallengeAge            time.Duration
+	errorOnInvalid             bool
+	fieldMap                   map[string]string
+	responseFieldMap           map[string]string
+	breaker                    *circuitBreaker
+	failures                   *failureCache
+	replays                    *replayTracker
+	rejectReplays              bool
+	validationSuccessPredicate func(*ValidateChallengeResponse) bool
+	errorClassifier            func(statusCode int, body []byte) error
+	statusHandlers             map[int]func(*http.Response) error
+	history                    *requestHistory
+	traceTimings               bool
+	defaultCustomData          interface{}
+	sendCustomData             bool
+	includeKeyFingerprint      bool
+	events                     chan<- Event
+	hmacPool                   *sync.Pool
+	newHash                    func() hash.Hash // nil for SHA-256
+
+	offlineMu      sync.RWMutex
+	offlineVectors map[string][]string // Challenge to accepted responses
+
+	capabilitiesMu        sync.Mutex
+	capabilities          *Capabilities
+	capabilitiesFetchedAt time.Time
+	capabilitiesTTL       time.Duration
 }
 
 // NewClient creates a new KeyClaimClient with the given API key
@@ -58,32 +433,259 @@ func NewClientWithSecret(apiKey, secret string) (*KeyClaimClient, error) {
 
 // NewClientWithConfig creates a new KeyClaimClient with a Config struct
 func NewClientWithConfig(config Config) (*KeyClaimClient, error) {
-	if config.APIKey == "" || !hasPrefix(config.APIKey, "kc_") {
-		return nil, fmt.Errorf("invalid API key format. API key must start with \"kc_\"")
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("%w. Pass the API key from your KeyClaim dashboard", ErrEmptyAPIKey)
+	}
+	if !hasPrefix(config.APIKey, "kc_") {
+		return nil, fmt.Errorf("%w. API key must start with \"kc_\"", ErrInvalidKeyPrefix)
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	if baseURL == "" {
+		// Decode default base URL from base64
+		baseURLBytes, err := base64.StdEncoding.DecodeString(defaultBaseURLB64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode base URL: %w", err)
+		}
+		baseURL = string(baseURLBytes)
+	}
+	// Paths are appended to base URLs, so drop trailing slashes to avoid "//"
+	baseURL = strings.TrimRight(baseURL, "/")
+	createBaseURL := strings.TrimRight(config.CreateBaseURL, "/")
+	validateBaseURL := strings.TrimRight(config.ValidateBaseURL, "/")
+	fallbackBaseURLs := make([]string, len(config.FallbackBaseURLs))
+	for i, endpoint := range config.FallbackBaseURLs {
+		fallbackBaseURLs[i] = strings.TrimRight(endpoint, "/")
+	}
+
+	logger := newRedactingLogger(config.Logger, config.APIKey, config.Secret, string(config.SecretBytes))
+
+	requireHTTPS := config.RequireHTTPS == nil || *config.RequireHTTPS
+	for _, endpoint := range append([]string{baseURL, createBaseURL, validateBaseURL}, fallbackBaseURLs...) {
+		if endpoint == "" {
+			continue
+		}
+		if err := checkHTTPS(endpoint); err != nil {
+			if requireHTTPS {
+				return nil, err
+			}
+			if logger != nil {
+				logger.Warn("keyclaim: base URL doesn't use https, the API key is sent in cleartext", "url", endpoint)
+			}
+		}
 	}
 
-	// Decode default base URL from base64
-	baseURLBytes, err := base64.StdEncoding.DecodeString(defaultBaseURLB64)
+	newHash, err := newHashFunc(config.HashAlgorithm)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode base URL: %w", err)
+		return nil, err
+	}
+
+	if config.Secret != "" && config.SecretBytes != nil {
+		return nil, fmt.Errorf("Secret and SecretBytes are mutually exclusive")
 	}
-	baseURL := string(baseURLBytes)
 
 	secret := config.Secret
-	if secret == "" {
+	if secret == "" && config.SecretBytes == nil {
+		if config.RequireExplicitSecret {
+			return nil, ErrSecretRequired
+		}
 		secret = config.APIKey
 	}
+	sharedSecret := secret == config.APIKey || string(config.SecretBytes) == config.APIKey
+	if sharedSecret && logger != nil && (config.WarnOnSharedSecret == nil || *config.WarnOnSharedSecret) {
+		logger.Warn("keyclaim: the secret is the API key, so anyone with the key can forge responses; set Secret to a separate value")
+	}
+
+	deriveKey := config.KeyDeriver
+	if deriveKey == nil {
+		deriveKey = func(secret string) []byte { return []byte(secret) }
+	}
+	var key []byte
+	if config.SecretBytes != nil {
+		key = append([]byte(nil), config.SecretBytes...)
+	} else {
+		key = deriveKey(secret)
+	}
+	additionalKeys := make([][]byte, 0, len(config.AdditionalSecrets))
+	for _, additionalSecret := range config.AdditionalSecrets {
+		additionalKeys = append(additionalKeys, deriveKey(additionalSecret))
+	}
+
+	httpClient := &http.Client{
+		Timeout: defaultTimeout,
+	}
+
+	var tlsConfig *tls.Config
+	if config.InsecureSkipVerify {
+		tlsConfig = &tls.Config{InsecureSkipVerify: true}
+
+		if logger != nil {
+			logger.Warn("keyclaim: TLS certificate verification is disabled, do not use this in production")
+		}
+	}
+	if len(config.PinnedCertSHA256) > 0 {
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		tlsConfig.VerifyPeerCertificate = verifyCertPins(config.PinnedCertSHA256)
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultBackoff
+	}
+
+	capabilitiesTTL := config.CapabilitiesTTL
+	if capabilitiesTTL <= 0 {
+		capabilitiesTTL = defaultCapabilitiesTTL
+	}
+
+	accept := config.Accept
+	if accept == "" {
+		accept = defaultAccept
+	}
+
+	requestIDFromContext := config.RequestIDFromContext
+	if requestIDFromContext == nil {
+		requestIDFromContext = RequestIDFromContext
+	}
+
+	var slots chan struct{}
+	if config.MaxConcurrency > 0 {
+		slots = make(chan struct{}, config.MaxConcurrency)
+	}
+
+	var doer Doer = httpClient
+	if config.Doer != nil {
+		doer = config.Doer
+	}
 
 	return &KeyClaimClient{
 		apiKey:  config.APIKey,
 		baseURL: baseURL,
-		secret:  secret,
-		client: &http.Client{
-			Timeout: defaultTimeout,
-		},
+		client:  httpClient,
+		doer:    doer,
+		logger:  logger,
+
+		accept:                     accept,
+		maxRetries:                 config.MaxRetries,
+		retryBackoff:               retryBackoff,
+		unwrapData:                 config.UnwrapData,
+		signRequests:               config.SignRequests,
+		bodyInterceptor:            config.BodyInterceptor,
+		challengeSource:            config.ChallengeSource,
+		decryptor:                  config.Decryptor,
+		debugHook:                  config.DebugHook,
+		customDataValidator:        config.CustomDataValidator,
+		auditHook:                  config.AuditHook,
+		autoRefreshExpired:         config.AutoRefreshExpired,
+		key:                        key,
+		customSecret:               !sharedSecret,
+		additionalKeys:             additionalKeys,
+		capabilitiesTTL:            capabilitiesTTL,
+		echoTransform:              config.EchoTransform,
+		createBaseURL:              createBaseURL,
+		validateBaseURL:            validateBaseURL,
+		fallbackBaseURLs:           fallbackBaseURLs,
+		validateChallengeEncoding:  config.ValidateChallengeEncoding,
+		requestIDFromContext:       requestIDFromContext,
+		slots:                      slots,
+		failFastOnConcurrency:      config.FailFastOnConcurrency,
+		strictTTL:                  config.StrictTTL,
+		ttlUnit:                    config.TTLUnit,
+		strictDecode:               config.StrictDecode,
+		maxChallengeAge:            config.MaxChallengeAge,
+		errorOnInvalid:             config.ErrorOnInvalid,
+		fieldMap:                   maps.Clone(config.FieldMap),
+		responseFieldMap:           invertFieldMap(config.FieldMap),
+		breaker:                    newCircuitBreaker(config.CircuitBreaker),
+		failures:                   newFailureCache(config.FailureCache),
+		replays:                    newReplayTracker(config.ReplayWindow),
+		rejectReplays:              config.RejectReplays,
+		validationSuccessPredicate: config.ValidationSuccessPredicate,
+		errorClassifier:            config.ErrorClassifier,
+		statusHandlers:             maps.Clone(config.StatusHandlers),
+		history:                    newRequestHistory(config.HistorySize),
+		traceTimings:               config.TraceTimings,
+		defaultCustomData:          config.DefaultCustomData,
+		sendCustomData:             config.SendCustomData,
+		includeKeyFingerprint:      config.IncludeKeyFingerprint,
+		events:                     config.Events,
+		hmacPool:                   newHMACPool(key),
+		newHash:                    newHash,
 	}, nil
 }
 
+// verifyCertPins returns a tls.Config.VerifyPeerCertificate callback that
+// rejects leaf certificates whose SHA-256 fingerprint isn't one of pins
+func verifyCertPins(pins []string) func([][]byte, [][]*x509.Certificate) error {
+	allowed := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		allowed[strings.ToLower(strings.ReplaceAll(pin, ":", ""))] = true
+	}
+
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return ErrCertificatePinMismatch
+		}
+		fingerprint := sha256.Sum256(rawCerts[0])
+		if !allowed[hex.EncodeToString(fingerprint[:])] {
+			return fmt.Errorf("%w: got %x", ErrCertificatePinMismatch, fingerprint)
+		}
+		return nil
+	}
+}
+
+// checkHTTPS returns an error wrapping ErrInsecureBaseURL unless baseURL is
+// an https URL
+func checkHTTPS(baseURL string) error {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("%w: %s. Set RequireHTTPS to false for local development", ErrInsecureBaseURL, baseURL)
+	}
+	return nil
+}
+
+// SetAPIKey atomically replaces the API key sent in the Authorization header,
+// for keys re-issued without a redeploy. Requests already built keep the
+// previous key. The secret used to generate responses is unaffected, even
+// when it defaulted to the previous API key.
+func (c *KeyClaimClient) SetAPIKey(apiKey string) error {
+	if apiKey == "" {
+		return ErrEmptyAPIKey
+	}
+	if !hasPrefix(apiKey, "kc_") {
+		return fmt.Errorf("%w. API key must start with \"kc_\"", ErrInvalidKeyPrefix)
+	}
+
+	if logger, ok := c.logger.(*redactingLogger); ok {
+		logger.addSecret(apiKey)
+	}
+
+	c.apiKeyMu.Lock()
+	c.apiKey = apiKey
+	c.apiKeyMu.Unlock()
+	return nil
+}
+
+// currentAPIKey returns the API key, which SetAPIKey may replace concurrently
+func (c *KeyClaimClient) currentAPIKey() string {
+	c.apiKeyMu.RLock()
+	defer c.apiKeyMu.RUnlock()
+	return c.apiKey
+}
+
 // CreateChallengeOptions holds options for creating a challenge
 type CreateChallengeOptions struct {
 	TTL int `json:"ttl,omitempty"`
@@ -91,35 +693,111 @@ type CreateChallengeOptions struct {
 
 // CreateChallengeResponse represents the response from creating a challenge
 type CreateChallengeResponse struct {
-	Challenge string `json:"challenge"`
-	ExpiresIn int    `json:"expires_in"`
-	Encrypted *bool  `json:"encrypted,omitempty"`
+	Challenge   string `json:"challenge"`
+	ExpiresIn   int    `json:"expires_in"`
+	Encrypted   *bool  `json:"encrypted,omitempty"`
+	ChallengeID string `json:"id,omitempty"` // Server-assigned ID, if provided
+
+	// Method is the response method the server expects, if it hints one.
+	// ValidateHinted uses it.
+	Method ResponseMethod `json:"method,omitempty"`
+
+	// ExpiresAt is when the challenge expires, by the local clock. CreateChallenge
+	// fills it in from ExpiresIn when the server doesn't send it, so that it
+	// survives serialization, e.g. for ValidateFromChallengeJSON. A time sent
+	// by the server is corrected by ClockSkew.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// ClockSkew is how far the server's clock is ahead of the local one, as
+	// estimated by CreateChallenge from the response's Date header. Date has
+	// one-second resolution, so differences of a second or less are reported
+	// as zero.
+	ClockSkew time.Duration `json:"-"`
+
+	// ReceivedAt is when CreateChallenge or StreamChallenges received the
+	// challenge, used by AgeExceeds and Config.MaxChallengeAge
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+}
+
+// AgeExceeds reports whether the challenge was received more than max ago.
+// It is false when the receipt time is unknown.
+func (r *CreateChallengeResponse) AgeExceeds(max time.Duration) bool {
+	return r.ReceivedAt != nil && time.Since(*r.ReceivedAt) > max
+}
+
+// received records that the challenge was received at receivedAt from a
+// server whose clock is skew ahead, setting ReceivedAt, ClockSkew and
+// ExpiresAt
+func (r *CreateChallengeResponse) received(receivedAt time.Time, skew time.Duration) {
+	r.ReceivedAt = &receivedAt
+	r.ClockSkew = skew
+	if r.ExpiresAt == nil {
+		expiresAt := receivedAt.Add(time.Duration(r.ExpiresIn) * time.Second)
+		r.ExpiresAt = &expiresAt
+	} else if skew != 0 {
+		// The server's expiry is by its own clock
+		expiresAt := r.ExpiresAt.Add(-skew)
+		r.ExpiresAt = &expiresAt
+	}
+}
+
+// expiresAt returns ExpiresAt, or ExpiresIn counted from now when it's unset
+func (r *CreateChallengeResponse) expiresAt() time.Time {
+	if r.ExpiresAt != nil {
+		return *r.ExpiresAt
+	}
+	return time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
 }
 
 // CreateChallenge creates a new challenge
 func (c *KeyClaimClient) CreateChallenge(ttl int) (*CreateChallengeResponse, error) {
+	return c.CreateChallengeContext(context.Background(), ttl)
+}
+
+// CreateChallengeContext creates a new challenge, honoring ctx cancellation
+func (c *KeyClaimClient) CreateChallengeContext(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+	return c.createChallenge(ctx, time.Duration(ttl)*time.Second, nil)
+}
+
+// CreateChallengeDuration creates a new challenge with the TTL given as a
+// duration. It is converted to Config.TTLUnit, rounding up to a whole unit.
+func (c *KeyClaimClient) CreateChallengeDuration(ttl time.Duration) (*CreateChallengeResponse, error) {
+	return c.CreateChallengeDurationContext(context.Background(), ttl)
+}
+
+// CreateChallengeDurationContext is CreateChallengeDuration, honoring ctx
+// cancellation
+func (c *KeyClaimClient) CreateChallengeDurationContext(ctx context.Context, ttl time.Duration) (*CreateChallengeResponse, error) {
+	return c.createChallenge(ctx, ttl, nil)
+}
+
+// CreateChallengeWithMeta creates a new challenge and also returns metadata
+// about the HTTP exchange, such as latency and the number of attempts
+func (c *KeyClaimClient) CreateChallengeWithMeta(ctx context.Context, ttl int) (*CreateChallengeResponse, *ResponseMeta, error) {
+	meta := &ResponseMeta{}
+	challenge, err := c.createChallenge(ctx, time.Duration(ttl)*time.Second, meta)
+	return challenge, meta, err
+}
+
+func (c *KeyClaimClient) createChallenge(ctx context.Context, ttl time.Duration, meta *ResponseMeta) (*CreateChallengeResponse, error) {
 	if ttl == 0 {
-		ttl = defaultTTL
+		seconds, err := c.resolveTTL(0)
+		if err != nil {
+			return nil, err
+		}
+		ttl = time.Duration(seconds) * time.Second
 	}
 
 	reqBody := map[string]interface{}{
-		"ttl": ttl,
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		"ttl": c.ttlValue(ttl),
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/api/challenge/create", bytes.NewBuffer(jsonData))
+	req, err := c.newRequestAt(ctx, orDefault(c.createBaseURL, c.baseURL), "POST", "/api/challenge/create", reqBody, false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
-
-	resp, err := c.client.Do(req)
+	resp, err := c.do(req, meta)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create challenge: %w", err)
 	}
@@ -129,33 +807,112 @@ func (c *KeyClaimClient) CreateChallenge(ttl int) (*CreateChallengeResponse, err
 		return nil, c.handleErrorResponse(resp, "Failed to create challenge")
 	}
 
+	if err := checkContentType(resp); err != nil {
+		return nil, err
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
 	var challengeResp CreateChallengeResponse
-	if err := json.NewDecoder(resp.Body).Decode(&challengeResp); err != nil {
+	bodyBytes = renameFields(bodyBytes, c.responseFieldMap)
+	if err := json.Unmarshal(bodyBytes, &challengeResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	if err := c.checkUnknownFields(bodyBytes, &challengeResp); err != nil {
+		return nil, err
+	}
+	receivedAt := time.Now()
+	challengeResp.received(receivedAt, serverClockSkew(resp, receivedAt))
+
+	c.emit(Event{Type: EventChallengeCreated, Challenge: challengeResp.Challenge, ExpiresIn: challengeResp.ExpiresIn})
 
 	return &challengeResp, nil
 }
 
+// serverClockSkew estimates how far the server's clock is ahead of the local
+// one from resp's Date header, compared with receivedAt. It is zero without a
+// parseable header or within the header's one-second resolution, which can
+// put the server a second behind when the response straddles a tick.
+func serverClockSkew(resp *http.Response, receivedAt time.Time) time.Duration {
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0
+	}
+
+	skew := serverTime.Sub(receivedAt.Truncate(time.Second))
+	if skew >= -time.Second && skew <= time.Second {
+		return 0
+	}
+	return skew
+}
+
+// resolveTTL applies the default TTL to a zero ttl, or rejects it when
+// Config.StrictTTL is set
+func (c *KeyClaimClient) resolveTTL(ttl int) (int, error) {
+	if ttl != 0 {
+		return ttl, nil
+	}
+	if c.strictTTL {
+		return 0, ErrZeroTTL
+	}
+	return defaultTTL, nil
+}
+
+// ttlValue converts ttl to the number sent to the server in c.ttlUnit,
+// rounding up so that a sub-unit TTL isn't sent as zero
+func (c *KeyClaimClient) ttlValue(ttl time.Duration) int64 {
+	unit := time.Second
+	if c.ttlUnit == TTLUnitMilliseconds {
+		unit = time.Millisecond
+	}
+
+	value := int64(ttl / unit)
+	if ttl%unit > 0 {
+		value++
+	}
+	return value
+}
+
 // GenerateResponse generates a response from a challenge using the specified method
 func (c *KeyClaimClient) GenerateResponse(challenge string, method ResponseMethod, customData interface{}) (string, error) {
+	return c.generateResponse(c.key, challenge, method, customData)
+}
+
+func (c *KeyClaimClient) generateResponse(key []byte, challenge string, method ResponseMethod, customData interface{}) (string, error) {
+	if c.validateChallengeEncoding {
+		if err := checkChallengeEncoding(challenge); err != nil {
+			return "", err
+		}
+	}
+
 	switch method {
 	case ResponseMethodEcho:
+		if c.echoTransform != nil {
+			return c.echoTransform(challenge), nil
+		}
 		return challenge, nil
 
 	case ResponseMethodHMAC:
-		h := hmac.New(sha256.New, []byte(c.secret))
-		h.Write([]byte(challenge))
-		return hex.EncodeToString(h.Sum(nil)), nil
+		return c.hmacHex(key, challenge), nil
 
 	case ResponseMethodHash:
-		hash := sha256.Sum256([]byte(challenge + c.secret))
-		return hex.EncodeToString(hash[:]), nil
+		return c.hashHex(challenge, key), nil
 
 	case ResponseMethodCustom:
+		if customData == nil {
+			customData = c.defaultCustomData
+		}
 		if customData == nil {
 			return "", fmt.Errorf("custom data is required for custom method")
 		}
+		if c.customDataValidator != nil {
+			if err := c.customDataValidator(customData); err != nil {
+				return "", fmt.Errorf("invalid custom data: %w", err)
+			}
+		}
 
 		var data string
 		switch v := customData.(type) {
@@ -169,133 +926,1673 @@ func (c *KeyClaimClient) GenerateResponse(challenge string, method ResponseMetho
 			data = challenge + ":" + string(jsonData)
 		}
 
-		hash := sha256.Sum256([]byte(data))
-		return hex.EncodeToString(hash[:]), nil
+		return sha256Hex(data, nil), nil
 
 	default:
 		return "", fmt.Errorf("unknown response method: %s", method)
 	}
 }
 
-// ValidateChallengeOptions holds options for validating a challenge
-type ValidateChallengeOptions struct {
-	Challenge         string `json:"challenge"`
-	Response          string `json:"response"`
-	DecryptedChallenge *string `json:"decryptedChallenge,omitempty"`
-}
-
-// ValidateChallengeResponse represents the response from validating a challenge
-type ValidateChallengeResponse struct {
-	Valid    *bool  `json:"valid"`
-	Signature *string `json:"signature,omitempty"`
-	Quota    *Quota `json:"quota,omitempty"`
-	Error    *string `json:"error,omitempty"`
-}
+// GenerateResponseFromReader generates a ResponseMethodCustom response with
+// the custom data streamed from r, so large payloads don't need to be held in
+// memory. The hash is fed, in order, the challenge, a ":" separator and the
+// bytes of r, which makes the result identical to GenerateResponse with
+// ResponseMethodCustom and the same data as a string.
+func (c *KeyClaimClient) GenerateResponseFromReader(challenge string, r io.Reader) (string, error) {
+	if c.validateChallengeEncoding {
+		if err := checkChallengeEncoding(challenge); err != nil {
+			return "", err
+		}
+	}
 
-// Quota represents quota information
-type Quota struct {
-	Used      int         `json:"used"`
-	Remaining int         `json:"remaining"`
-	Quota     interface{} `json:"quota"` // Can be int or "unlimited"
+	h := sha256.New()
+	h.Write([]byte(challenge + ":"))
+	if _, err := io.Copy(h, r); err != nil {
+		return "", fmt.Errorf("failed to read custom data: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-// ValidateChallenge validates a challenge-response pair
-func (c *KeyClaimClient) ValidateChallenge(challenge, response string, decryptedChallenge *string) (*ValidateChallengeResponse, error) {
-	reqBody := ValidateChallengeOptions{
-		Challenge: challenge,
-		Response:  response,
+// GenerateResponseWithNonce generates a response like GenerateResponse, but
+// mixes a random nonce into the pre-image so that two responses for the same
+// challenge differ. The nonce (32 hex chars) is returned so it can be sent to
+// the server for verification.
+//
+// The nonce is incorporated by substituting challenge + ":" + nonce for the
+// challenge before applying method, e.g. for ResponseMethodCustom the hashed
+// pre-image is challenge + ":" + nonce + ":" + customData.
+func (c *KeyClaimClient) GenerateResponseWithNonce(challenge string, method ResponseMethod, customData interface{}) (response, nonce string, err error) {
+	nonce, err = generateNonce()
+	if err != nil {
+		return "", "", err
 	}
 
-	if decryptedChallenge != nil {
-		reqBody.DecryptedChallenge = decryptedChallenge
+	response, err = c.GenerateResponse(challenge+":"+nonce, method, customData)
+	if err != nil {
+		return "", "", err
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	return response, nonce, nil
+}
+
+// GenerateResponseWithSalt generates a response like GenerateResponse, with a
+// random salt (32 hex chars) mixed into the pre-image, for server modes that
+// require one. Pass the returned salt as ValidateChallengeOptions.Salt so the
+// server can recompute the response.
+//
+// The salt is incorporated like a nonce, by substituting
+// challenge + ":" + salt for the challenge before applying method:
+//
+//	HMAC-SHA256(secret, challenge + ":" + salt)         for ResponseMethodHMAC
+//	SHA-256(challenge + ":" + salt + secret)            for ResponseMethodHash
+//	SHA-256(challenge + ":" + salt + ":" + customData)  for ResponseMethodCustom
+//
+// For ResponseMethodEcho the response is the salted challenge itself.
+func (c *KeyClaimClient) GenerateResponseWithSalt(challenge string, method ResponseMethod, customData interface{}) (response, salt string, err error) {
+	salt, err = generateNonce()
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return "", "", err
 	}
 
-	req, err := http.NewRequest("POST", c.baseURL+"/api/challenge/validate", bytes.NewBuffer(jsonData))
+	response, err = c.GenerateResponse(challenge+":"+salt, method, customData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return "", "", err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	return response, salt, nil
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to validate challenge: %w", err)
+// GenerateResponseAt generates an HMAC or hash response bound to ts, so the
+// server can reject responses replayed outside its freshness window. The
+// timestamp (Unix seconds) is returned so it can be sent alongside.
+//
+// The timestamp is incorporated by substituting challenge + ":" + timestamp
+// for the challenge before applying method, so the canonical pre-images are
+//
+//	HMAC-SHA256(secret, challenge + ":" + timestamp)  for ResponseMethodHMAC
+//	SHA-256(challenge + ":" + timestamp + secret)     for ResponseMethodHash
+func (c *KeyClaimClient) GenerateResponseAt(challenge string, method ResponseMethod, ts time.Time) (response, timestamp string, err error) {
+	if method != ResponseMethodHMAC && method != ResponseMethodHash {
+		return "", "", fmt.Errorf("timestamped responses require the hmac or hash method, got %q", method)
 	}
-	defer resp.Body.Close()
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+	timestamp = strconv.FormatInt(ts.Unix(), 10)
+	response, err = c.GenerateResponse(challenge+":"+timestamp, method, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", "", err
 	}
 
-	var validationResp ValidateChallengeResponse
-	if err := json.Unmarshal(bodyBytes, &validationResp); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	return response, timestamp, nil
+}
+
+// generateNonce returns 16 random bytes, hex-encoded
+func generateNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
 	}
+	return hex.EncodeToString(nonce), nil
+}
 
-	// If the API returns a validation response (even if invalid), return it
-	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
-		if validationResp.Valid != nil {
-			return &validationResp, nil
+// checkChallengeEncoding returns an error wrapping ErrInvalidChallengeEncoding
+// if challenge isn't valid UTF-8 or contains non-printable characters
+func checkChallengeEncoding(challenge string) error {
+	if !utf8.ValidString(challenge) {
+		return fmt.Errorf("%w: not valid UTF-8", ErrInvalidChallengeEncoding)
+	}
+	for i, r := range challenge {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("%w: non-printable character %U at byte %d", ErrInvalidChallengeEncoding, r, i)
 		}
 	}
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, c.handleErrorResponseFromBody(bodyBytes, resp.StatusCode, "Failed to validate challenge")
+// VerifyResponse reports whether response is what the given method produces
+// for challenge with this client's secret, or any of Config.AdditionalSecrets.
+// Comparisons are constant-time and every secret is always tried, so it can be
+// used by a verifier holding the secret without calling the API.
+func (c *KeyClaimClient) VerifyResponse(challenge, response string, method ResponseMethod, customData interface{}) (bool, error) {
+	match := 0
+	for _, key := range c.verificationKeys() {
+		expected, err := c.generateResponse(key, challenge, method, customData)
+		if err != nil {
+			return false, err
+		}
+		match |= subtle.ConstantTimeCompare([]byte(expected), []byte(response))
 	}
 
-	return &validationResp, nil
+	return match == 1, nil
 }
 
-// Validate completes the full flow: create challenge, generate response, and validate
-func (c *KeyClaimClient) Validate(method ResponseMethod, ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
-	// Create challenge
-	challenge, err := c.CreateChallenge(ttl)
+// ValidateLocally mirrors the API's validation of response for challenge
+// without a network call, for unit tests and offline development. Like the
+// API's valid flag, a wrong or empty response is reported as false rather
+// than an error; an error means the inputs couldn't be validated at all,
+// such as an unsupported method.
+func (c *KeyClaimClient) ValidateLocally(challenge, response string, method ResponseMethod, customData interface{}) (bool, error) {
+	if response == "" {
+		return false, nil
+	}
+	return c.VerifyResponse(challenge, response, method, customData)
+}
+
+// verificationKeys returns the primary key followed by the keys of the
+// additional secrets accepted during a rotation window
+func (c *KeyClaimClient) verificationKeys() [][]byte {
+	return append([][]byte{c.key}, c.additionalKeys...)
+}
+
+// GenerateProof returns both the echo and the HMAC response for challenge,
+// for servers whose validation contract takes them in separate fields
+func (c *KeyClaimClient) GenerateProof(challenge string) (echo, hmac string, err error) {
+	echo, err = c.GenerateResponse(challenge, ResponseMethodEcho, nil)
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	// Generate response
-	response, err := c.GenerateResponse(challenge.Challenge, method, customData)
+	hmac, err = c.GenerateResponse(challenge, ResponseMethodHMAC, nil)
 	if err != nil {
-		return nil, err
+		return "", "", err
 	}
 
-	// Validate
-	return c.ValidateChallenge(challenge.Challenge, response, nil)
+	return echo, hmac, nil
 }
 
-// IsValid checks if a validation response is valid
-func (v *ValidateChallengeResponse) IsValid() bool {
-	return v.Valid != nil && *v.Valid
-}
+// GenerateAllResponses generates a response for every supported method.
+// The custom method is only included when customData or
+// Config.DefaultCustomData is set. Intended for diagnosing interop issues
+// with other SDKs or server implementations.
+func (c *KeyClaimClient) GenerateAllResponses(challenge string, customData interface{}) (map[ResponseMethod]string, error) {
+	methods := []ResponseMethod{ResponseMethodEcho, ResponseMethodHMAC, ResponseMethodHash}
+	if customData != nil || c.defaultCustomData != nil {
+		methods = append(methods, ResponseMethodCustom)
+	}
 
-// KeyClaimError represents an error from the KeyClaim API
-type KeyClaimError struct {
-	Message    string
-	Code       string
+	responses := make(map[ResponseMethod]string, len(methods))
+	for _, method := range methods {
+		response, err := c.GenerateResponse(challenge, method, customData)
+		if err != nil {
+			return nil, err
+		}
+		responses[method] = response
+	}
+
+	return responses, nil
+}
+
+// SignedChallenge pairs a challenge with the response generated for it, for
+// submission with ValidateChallenge once connectivity returns
+type SignedChallenge struct {
+	Challenge string         `json:"challenge"`
+	Response  string         `json:"response"`
+	Method    ResponseMethod `json:"method"`
+}
+
+// PreSign generates responses for a batch of challenges fetched earlier,
+// without contacting the API, for offline-first workflows. The custom method
+// uses Config.DefaultCustomData. Results are in the order of challenges; the
+// first failure aborts the batch.
+func (c *KeyClaimClient) PreSign(challenges []string, method ResponseMethod) ([]SignedChallenge, error) {
+	signed := make([]SignedChallenge, len(challenges))
+	for i, challenge := range challenges {
+		response, err := c.GenerateResponse(challenge, method, nil)
+		if err != nil {
+			return nil, fmt.Errorf("challenge %d: %w", i, err)
+		}
+		signed[i] = SignedChallenge{Challenge: challenge, Response: response, Method: method}
+	}
+
+	return signed, nil
+}
+
+// ValidateChallengeOptions holds options for validating a challenge
+type ValidateChallengeOptions struct {
+	Challenge          string  `json:"challenge"`
+	Response           string  `json:"response"`
+	DecryptedChallenge *string `json:"decryptedChallenge,omitempty"`
+	ChallengeID        string  `json:"challenge_id,omitempty"` // From CreateChallengeResponse, for server-side correlation
+	Salt               string  `json:"salt,omitempty"`         // From GenerateResponseWithSalt, sent only when set
+
+	// CustomData is the custom method's data, sent as-is alongside the
+	// response for servers that recompute it. Validate fills it in when
+	// Config.SendCustomData is set.
+	CustomData interface{} `json:"custom_data,omitempty"`
+
+	// KeyFingerprint identifies the API key for servers with several keys.
+	// Filled in with KeyClaimClient.KeyFingerprint when
+	// Config.IncludeKeyFingerprint is set.
+	KeyFingerprint string `json:"key_fingerprint,omitempty"`
+
+	// Metadata holds optional extra fields, such as client or device
+	// information, merged into the request body. Entries named like one of
+	// the fields above, or like the server name Config.FieldMap gives one of
+	// them, are ignored.
+	Metadata map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON encodes the options as the validate request body, with
+// Metadata merged in at the top level
+func (o ValidateChallengeOptions) MarshalJSON() ([]byte, error) {
+	type options ValidateChallengeOptions
+	body, err := json.Marshal(options(o))
+	if err != nil || len(o.Metadata) == 0 {
+		return body, err
+	}
+
+	merged := make(map[string]interface{}, len(o.Metadata)+7)
+	for name, value := range o.Metadata {
+		merged[name] = value
+	}
+	for _, name := range []string{"challenge", "response", "decryptedChallenge", "challenge_id", "salt", "custom_data", "key_fingerprint"} {
+		delete(merged, name)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	for name, value := range fields {
+		merged[name] = value
+	}
+
+	return json.Marshal(merged)
+}
+
+// ValidateChallengeResponse represents the response from validating a challenge
+type ValidateChallengeResponse struct {
+	Valid    *bool  `json:"valid"`
+	Signature *string `json:"signature,omitempty"`
+	Quota    *Quota `json:"quota,omitempty"`
+	Error    *string `json:"error,omitempty"`
+
+	// Results holds the server's verdict for each method, for ValidateMulti
+	Results map[ResponseMethod]bool `json:"results,omitempty"`
+}
+
+// Quota represents quota information
+type Quota struct {
+	Used      int         `json:"used"`
+	Remaining int         `json:"remaining"`
+	Quota     interface{} `json:"quota"`              // An int, a float64 for a fractional limit, or "unlimited"
+	ResetAt   *time.Time  `json:"reset_at,omitempty"` // When the quota resets, if provided
+}
+
+// UnmarshalJSON decodes a quota, accepting Used and Remaining as JSON
+// numbers or as numeric strings ("used": "10"), as some encoders emit. An
+// integral limit is stored in Quota as an int whether it was encoded as 100
+// or 100.0, rather than the float64 a plain interface{} would hold.
+func (q *Quota) UnmarshalJSON(data []byte) error {
+	type quota Quota
+	var raw struct {
+		*quota
+		Used      json.RawMessage `json:"used"`
+		Remaining json.RawMessage `json:"remaining"`
+		Quota     json.RawMessage `json:"quota"`
+	}
+	raw.quota = (*quota)(q)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	if q.Used, err = decodeLenientInt(raw.Used); err != nil {
+		return fmt.Errorf("quota used: %w", err)
+	}
+	if q.Remaining, err = decodeLenientInt(raw.Remaining); err != nil {
+		return fmt.Errorf("quota remaining: %w", err)
+	}
+	if q.Quota, err = decodeQuotaLimit(raw.Quota); err != nil {
+		return fmt.Errorf("quota limit: %w", err)
+	}
+	return nil
+}
+
+// decodeQuotaLimit decodes the quota limit: integral numbers as int, other
+// numbers as float64 and strings such as "unlimited" as they are. Missing and
+// null values decode to nil.
+func decodeQuotaLimit(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	number, ok := v.(json.Number)
+	if !ok {
+		return v, nil
+	}
+	if n, err := number.Int64(); err == nil {
+		return int(n), nil
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", raw)
+	}
+	if f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+		return int(f), nil
+	}
+	return f, nil
+}
+
+// decodeLenientInt decodes an integer given as a JSON number, including an
+// integral float such as 10.0, or as a string holding one. Missing and null
+// values decode to 0.
+func decodeLenientInt(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return 0, err
+		}
+		raw = json.RawMessage(strings.TrimSpace(s))
+	}
+
+	// Only JSON number syntax is accepted, not e.g. "NaN" or "0x10"
+	var number json.Number
+	if err := json.Unmarshal(raw, &number); err != nil || raw[0] == '"' {
+		return 0, fmt.Errorf("invalid integer %s", raw)
+	}
+
+	if n, err := number.Int64(); err == nil {
+		return int(n), nil
+	}
+	f, err := number.Float64()
+	if err != nil || f != math.Trunc(f) {
+		return 0, fmt.Errorf("invalid integer %s", raw)
+	}
+	return int(f), nil
+}
+
+// PercentUsed returns the percentage of the quota used. The second result is
+// false when the quota is unlimited or its limit is missing or zero.
+func (q *Quota) PercentUsed() (float64, bool) {
+	limit, ok := q.limit()
+	if !ok || limit == 0 {
+		return 0, false
+	}
+	return float64(q.Used) / limit * 100, true
+}
+
+// RemainingValidations returns roughly how many more validations the quota
+// allows, from Remaining, never negative. The second result is false when the
+// quota is unlimited, in which case the count is meaningless.
+func (q *Quota) RemainingValidations() (int, bool) {
+	if q.IsUnlimited() {
+		return 0, false
+	}
+	if q.Remaining < 0 {
+		return 0, true
+	}
+	return q.Remaining, true
+}
+
+// IsUnlimited reports whether the quota is "unlimited", in any case
+func (q *Quota) IsUnlimited() bool {
+	s, ok := q.Quota.(string)
+	return ok && strings.EqualFold(s, "unlimited")
+}
+
+// Limit returns the quota limit as an int, however it was decoded or set;
+// a fractional limit is truncated. The second result is false when the quota
+// is unlimited or its limit is missing.
+func (q *Quota) Limit() (int, bool) {
+	limit, ok := q.limit()
+	return int(limit), ok
+}
+
+// limit returns the numeric quota limit, or false when it is unlimited or missing
+func (q *Quota) limit() (float64, bool) {
+	switch v := q.Quota.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// ValidateChallenge validates a challenge-response pair
+func (c *KeyClaimClient) ValidateChallenge(challenge, response string, decryptedChallenge *string) (*ValidateChallengeResponse, error) {
+	return c.ValidateChallengeContext(context.Background(), challenge, response, decryptedChallenge)
+}
+
+// ValidateChallengeContext validates a challenge-response pair, honoring ctx cancellation
+func (c *KeyClaimClient) ValidateChallengeContext(ctx context.Context, challenge, response string, decryptedChallenge *string) (*ValidateChallengeResponse, error) {
+	return c.validateChallenge(ctx, ValidateChallengeOptions{
+		Challenge:          challenge,
+		Response:           response,
+		DecryptedChallenge: decryptedChallenge,
+	}, nil)
+}
+
+// ValidateChallengeWithOptions validates a challenge-response pair described
+// by opts, which allows sending optional fields such as the challenge ID
+func (c *KeyClaimClient) ValidateChallengeWithOptions(ctx context.Context, opts ValidateChallengeOptions) (*ValidateChallengeResponse, error) {
+	return c.validateChallenge(ctx, opts, nil)
+}
+
+// ValidateChallengeWithMeta validates a challenge-response pair and also
+// returns metadata about the HTTP exchange, such as latency and the number
+// of attempts
+func (c *KeyClaimClient) ValidateChallengeWithMeta(ctx context.Context, challenge, response string, decryptedChallenge *string) (*ValidateChallengeResponse, *ResponseMeta, error) {
+	meta := &ResponseMeta{}
+	result, err := c.validateChallenge(ctx, ValidateChallengeOptions{
+		Challenge:          challenge,
+		Response:           response,
+		DecryptedChallenge: decryptedChallenge,
+	}, meta)
+	return result, meta, err
+}
+
+func (c *KeyClaimClient) validateChallenge(ctx context.Context, opts ValidateChallengeOptions, meta *ResponseMeta) (*ValidateChallengeResponse, error) {
+	if c.includeKeyFingerprint && opts.KeyFingerprint == "" {
+		opts.KeyFingerprint = c.KeyFingerprint()
+	}
+	if c.replays != nil && c.replays.seen(opts.Challenge, opts.Response) {
+		if c.logger != nil {
+			c.logger.Warn("keyclaim: submitting a challenge and response already submitted", "window", c.replays.window, "rejected", c.rejectReplays)
+		}
+		c.emit(Event{Type: EventReplaySuspected, Challenge: opts.Challenge})
+		if c.rejectReplays {
+			return nil, fmt.Errorf("%w: submitted within the last %s", ErrReplayDetected, c.replays.window)
+		}
+	}
+
+	var result *ValidateChallengeResponse
+	var err error
+	if cached, ok := c.failures.lookup(opts.Challenge); ok {
+		result, err = cached.result, cached.err
+	} else {
+		result, err = c.sendValidation(ctx, opts, meta)
+		c.failures.record(opts.Challenge, result, err)
+	}
+	if c.replays != nil && isAnswered(result, err) {
+		c.replays.record(opts.Challenge, opts.Response)
+	}
+	return c.finishValidation(result, err)
+}
+
+// finishValidation emits the validation event for a result and applies
+// Config.ErrorOnInvalid to it
+func (c *KeyClaimClient) finishValidation(result *ValidateChallengeResponse, err error) (*ValidateChallengeResponse, error) {
+	if err == nil && result.IsValid() {
+		c.emit(Event{Type: EventValidationSucceeded})
+	} else {
+		c.emit(Event{Type: EventValidationFailed, Err: err})
+	}
+	if err == nil && c.errorOnInvalid && !result.IsValid() {
+		err = newValidationFailedError(result)
+	}
+	return result, err
+}
+
+func (c *KeyClaimClient) sendValidation(ctx context.Context, opts ValidateChallengeOptions, meta *ResponseMeta) (*ValidateChallengeResponse, error) {
+	resp, err := c.doWithFallback(ctx, "POST", "/api/challenge/validate", opts, meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate challenge: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return c.decodeValidationResponse(resp, "Failed to validate challenge")
+}
+
+// decodeValidationResponse decodes a validation result from resp. Invalid
+// results reported with a 400 or 422 are returned as results rather than
+// errors; other non-200 responses become KeyClaimErrors. A 204 No Content
+// is success without a body, so it becomes a result with Valid set to true.
+func (c *KeyClaimClient) decodeValidationResponse(resp *http.Response, defaultMessage string) (*ValidateChallengeResponse, error) {
+	if resp.StatusCode == http.StatusNoContent {
+		valid := true
+		return &ValidateChallengeResponse{Valid: &valid}, nil
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	bodyBytes = renameFields(bodyBytes, c.responseFieldMap)
+
+	if err := checkContentType(resp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, withRequestID(c.handleErrorResponseFromBody(bodyBytes, resp.StatusCode, defaultMessage), resp)
+		}
+		return nil, err
+	}
+
+	if c.unwrapData {
+		bodyBytes = unwrapDataField(bodyBytes, "valid", "error")
+	}
+
+	var validationResp ValidateChallengeResponse
+	if err := json.Unmarshal(bodyBytes, &validationResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	// If the API returns a validation response (even if invalid), return it
+	if resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusUnprocessableEntity {
+		if validationResp.Valid != nil {
+			if err := c.checkUnknownFields(bodyBytes, &validationResp); err != nil {
+				return nil, err
+			}
+			return &validationResp, nil
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, withRequestID(c.handleErrorResponseFromBody(bodyBytes, resp.StatusCode, defaultMessage), resp)
+	}
+
+	if err := c.checkUnknownFields(bodyBytes, &validationResp); err != nil {
+		return nil, err
+	}
+	// A result without either field would otherwise pass for an invalid one
+	if validationResp.Valid == nil && validationResp.Error == nil {
+		return nil, ErrAmbiguousValidation
+	}
+	return &validationResp, nil
+}
+
+// checkUnknownFields decodes body into v again, rejecting fields v doesn't
+// have, when Config.StrictDecode is set. The body has already been decoded
+// leniently, so any error here is an unknown field.
+func (c *KeyClaimClient) checkUnknownFields(body []byte, v interface{}) error {
+	if !c.strictDecode {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnknownField, err)
+	}
+
+	// Quota decodes itself, out of reach of DisallowUnknownFields, so its
+	// fields are checked separately
+	var nested struct {
+		Quota json.RawMessage `json:"quota"`
+	}
+	if err := json.Unmarshal(body, &nested); err != nil || len(nested.Quota) == 0 || string(nested.Quota) == "null" {
+		return nil
+	}
+	if err := checkQuotaFields(nested.Quota); err != nil {
+		return fmt.Errorf("%w: quota: %v", ErrUnknownField, err)
+	}
+	return nil
+}
+
+// checkQuotaFields decodes a quota object rejecting fields Quota doesn't
+// have, the numeric fields being left raw as in Quota.UnmarshalJSON
+func checkQuotaFields(data []byte) error {
+	type quota Quota
+	var raw struct {
+		*quota
+		Used      json.RawMessage `json:"used"`
+		Remaining json.RawMessage `json:"remaining"`
+		Quota     json.RawMessage `json:"quota"`
+	}
+	raw.quota = new(quota)
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(&raw)
+}
+
+// Validate completes the full flow: create challenge, generate response, and validate
+func (c *KeyClaimClient) Validate(method ResponseMethod, ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
+	return c.ValidateContext(context.Background(), method, ttl, customData)
+}
+
+// ValidateContext completes the full flow like Validate, honoring ctx cancellation.
+// If Config.ChallengeSource is set, the challenge is obtained from it instead
+// of the create endpoint. If Config.AutoRefreshExpired is set and the
+// challenge expired, the flow is retried once with a fresh challenge.
+//
+// When Config.ValidationSuccessPredicate rejects a result the API reported
+// as valid, the result is returned along with ErrValidationRejected.
+// When ctx is canceled after the challenge was created, the error is a
+// *FlowCanceledError carrying that challenge.
+func (c *KeyClaimClient) ValidateContext(ctx context.Context, method ResponseMethod, ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
+	return c.runFlow(func() (*ValidateChallengeResponse, error) {
+		return c.validateFlow(ctx, method, ttl, customData)
+	})
+}
+
+// runFlow runs flow, once more with a fresh challenge when
+// Config.AutoRefreshExpired is set and the challenge expired, and applies
+// Config.ValidationSuccessPredicate to the result
+func (c *KeyClaimClient) runFlow(flow func() (*ValidateChallengeResponse, error)) (*ValidateChallengeResponse, error) {
+	result, err := flow()
+	if c.autoRefreshExpired && isChallengeExpired(result, err) {
+		result, err = flow()
+	}
+	if err == nil && result.IsValid() && !c.Succeeded(result) {
+		return result, ErrValidationRejected
+	}
+	return result, err
+}
+
+// ValidateHinted completes the full flow like Validate, using the response
+// method hinted by the server in CreateChallengeResponse.Method, or
+// ResponseMethodHMAC when the server doesn't hint one
+func (c *KeyClaimClient) ValidateHinted(ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
+	return c.ValidateHintedContext(context.Background(), ttl, customData)
+}
+
+// ValidateHintedContext is ValidateHinted honoring ctx cancellation
+func (c *KeyClaimClient) ValidateHintedContext(ctx context.Context, ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
+	return c.runFlow(func() (*ValidateChallengeResponse, error) {
+		challenge, err := c.obtainChallenge(ctx, ttl)
+		if err != nil {
+			return nil, err
+		}
+
+		method := challenge.Method
+		if method == "" {
+			method = ResponseMethodHMAC
+		}
+		return c.respondAndValidate(ctx, challenge, method, customData)
+	})
+}
+
+// ValidateWithBudget runs the full flow like ValidateContext, within a total
+// time budget covering challenge creation, response generation, validation
+// and all retries and backoff. When the budget runs out at any stage, the
+// error matches context.DeadlineExceeded.
+func (c *KeyClaimClient) ValidateWithBudget(ctx context.Context, method ResponseMethod, ttl int, customData interface{}, budget time.Duration) (*ValidateChallengeResponse, error) {
+	budgetCtx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+
+	result, err := c.ValidateContext(budgetCtx, method, ttl, customData)
+	if err != nil && ctx.Err() == nil && budgetCtx.Err() != nil && !errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("validation budget of %s exhausted: %w: %w", budget, context.DeadlineExceeded, err)
+	}
+	return result, err
+}
+
+// ValidateAuto runs the full flow with each response method in turn, hmac,
+// hash, custom (only when customData or Config.DefaultCustomData is set) and
+// then echo, until one validates. It is meant for diagnostics and onboarding,
+// when the method the server expects is unknown.
+func (c *KeyClaimClient) ValidateAuto(ttl int, customData interface{}) (ResponseMethod, *ValidateChallengeResponse, error) {
+	return c.ValidateAutoContext(context.Background(), ttl, customData)
+}
+
+// ValidateAutoContext is ValidateAuto honoring ctx cancellation. Each method
+// is tried at most once, with a fresh challenge. The method that validated is
+// returned with its result; when none does, the method is "" and the last
+// result is returned, with its *ValidationFailedError when
+// Config.ErrorOnInvalid is set. Like Validate, a method the API accepts but
+// Config.ValidationSuccessPredicate rejects is returned with its result and
+// ErrValidationRejected. Other errors stop the negotiation immediately.
+func (c *KeyClaimClient) ValidateAutoContext(ctx context.Context, ttl int, customData interface{}) (ResponseMethod, *ValidateChallengeResponse, error) {
+	methods := []ResponseMethod{ResponseMethodHMAC, ResponseMethodHash}
+	if customData != nil || c.defaultCustomData != nil {
+		methods = append(methods, ResponseMethodCustom)
+	}
+	methods = append(methods, ResponseMethodEcho)
+
+	var result *ValidateChallengeResponse
+	var invalidErr error // With Config.ErrorOnInvalid, the last method's error
+	for _, method := range methods {
+		if err := ctx.Err(); err != nil {
+			return "", nil, err
+		}
+
+		var err error
+		result, err = c.runFlow(func() (*ValidateChallengeResponse, error) {
+			return c.validateFlow(ctx, method, ttl, customData)
+		})
+		if errors.Is(err, ErrValidationRejected) {
+			return method, result, err
+		}
+		if err != nil && !errors.Is(err, ErrValidationFailed) {
+			return "", nil, err
+		}
+		if c.Succeeded(result) {
+			return method, result, nil
+		}
+		invalidErr = err
+	}
+
+	return "", result, invalidErr
+}
+
+// Succeeded reports whether result counts as a successful validation: the
+// result of Config.ValidationSuccessPredicate when set, otherwise IsValid
+func (c *KeyClaimClient) Succeeded(result *ValidateChallengeResponse) bool {
+	if result == nil {
+		return false
+	}
+	if c.validationSuccessPredicate != nil {
+		return c.validationSuccessPredicate(result)
+	}
+	return result.IsValid()
+}
+
+func (c *KeyClaimClient) validateFlow(ctx context.Context, method ResponseMethod, ttl int, customData interface{}) (*ValidateChallengeResponse, error) {
+	// Create challenge
+	challenge, err := c.obtainChallenge(ctx, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return c.respondAndValidate(ctx, challenge, method, customData)
+}
+
+// respondAndValidate generates the response to challenge, decrypting it
+// first if needed, and validates it before the challenge expires
+func (c *KeyClaimClient) respondAndValidate(ctx context.Context, challenge *CreateChallengeResponse, method ResponseMethod, customData interface{}) (*ValidateChallengeResponse, error) {
+	expiresAt := challenge.expiresAt()
+
+	opts, err := c.respond(ctx, challenge, method, customData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validating an expired challenge is bound to fail, so don't bother
+	if !time.Now().Before(expiresAt) {
+		return nil, ErrChallengeExpired
+	}
+	if c.maxChallengeAge > 0 {
+		if challenge.ReceivedAt == nil {
+			return nil, fmt.Errorf("%w: receipt time unknown, max %s", ErrStaleChallenge, c.maxChallengeAge)
+		}
+		if challenge.AgeExceeds(c.maxChallengeAge) {
+			return nil, fmt.Errorf("%w: received %s ago, max %s", ErrStaleChallenge, time.Since(*challenge.ReceivedAt).Round(time.Millisecond), c.maxChallengeAge)
+		}
+	}
+	validateCtx, cancel := context.WithDeadline(ctx, expiresAt)
+	defer cancel()
+
+	// Validate
+	result, err := c.ValidateChallengeWithOptions(validateCtx, opts)
+	if err != nil && ctx.Err() != nil {
+		return nil, &FlowCanceledError{Challenge: challenge, Err: err}
+	}
+	return result, err
+}
+
+// respond generates the response to challenge, decrypting it first if
+// needed, and returns the validate request for it
+func (c *KeyClaimClient) respond(ctx context.Context, challenge *CreateChallengeResponse, method ResponseMethod, customData interface{}) (ValidateChallengeOptions, error) {
+	// Responding to an encrypted challenge without decrypting it would only
+	// fail later with a confusing validation error
+	plaintext := challenge.Challenge
+	var decryptedChallenge *string
+	if challenge.Encrypted != nil && *challenge.Encrypted {
+		if c.decryptor == nil {
+			return ValidateChallengeOptions{}, ErrDecryptionRequired
+		}
+		var err error
+		plaintext, err = c.decryptor(ctx, challenge.Challenge)
+		if err != nil {
+			return ValidateChallengeOptions{}, fmt.Errorf("failed to decrypt challenge: %w", err)
+		}
+		decryptedChallenge = &plaintext
+	}
+
+	response, err := c.GenerateResponse(plaintext, method, customData)
+	if err != nil {
+		return ValidateChallengeOptions{}, err
+	}
+
+	opts := ValidateChallengeOptions{
+		Challenge:          challenge.Challenge,
+		Response:           response,
+		DecryptedChallenge: decryptedChallenge,
+		ChallengeID:        challenge.ChallengeID,
+	}
+	if c.sendCustomData && method == ResponseMethodCustom {
+		opts.CustomData = customData
+		if opts.CustomData == nil {
+			opts.CustomData = c.defaultCustomData
+		}
+	}
+	return opts, nil
+}
+
+// ValidateFromChallengeJSON completes the flow for a challenge created
+// elsewhere and passed on as a serialized CreateChallengeResponse, e.g.
+// through a message queue: it decodes the challenge, checks that it hasn't
+// expired, generates the response and validates it.
+//
+// Expiry is checked against ExpiresAt, which CreateChallenge fills in; when
+// data lacks it, ExpiresIn is counted from now.
+func (c *KeyClaimClient) ValidateFromChallengeJSON(data []byte, method ResponseMethod, customData interface{}) (*ValidateChallengeResponse, error) {
+	return c.ValidateFromChallengeJSONContext(context.Background(), data, method, customData)
+}
+
+// ValidateFromChallengeJSONContext is ValidateFromChallengeJSON honoring ctx
+// cancellation
+func (c *KeyClaimClient) ValidateFromChallengeJSONContext(ctx context.Context, data []byte, method ResponseMethod, customData interface{}) (*ValidateChallengeResponse, error) {
+	var challenge CreateChallengeResponse
+	if err := json.Unmarshal(data, &challenge); err != nil {
+		return nil, fmt.Errorf("failed to decode challenge: %w", err)
+	}
+	if challenge.Challenge == "" {
+		return nil, errors.New("failed to decode challenge: missing challenge field")
+	}
+
+	result, err := c.respondAndValidate(ctx, &challenge, method, customData)
+	if err == nil && result.IsValid() && !c.Succeeded(result) {
+		return result, ErrValidationRejected
+	}
+	return result, err
+}
+
+// isChallengeExpired reports whether a validation failed because the
+// challenge expired, either as an error or as an invalid result
+func isChallengeExpired(result *ValidateChallengeResponse, err error) bool {
+	if err != nil {
+		return errors.Is(err, ErrChallengeExpired)
+	}
+	return result != nil && result.Error != nil && isExpiredCode(*result.Error)
+}
+
+// isExpiredCode reports whether an API error code denotes an expired challenge
+func isExpiredCode(code string) bool {
+	return strings.EqualFold(code, "challenge_expired") || strings.EqualFold(code, "expired")
+}
+
+// obtainChallenge returns a challenge from the configured ChallengeSource,
+// falling back to the create endpoint
+func (c *KeyClaimClient) obtainChallenge(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+	if c.challengeSource == nil {
+		return c.CreateChallengeContext(ctx, ttl)
+	}
+
+	ttl, err := c.resolveTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := c.challengeSource(ctx, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain challenge from source: %w", err)
+	}
+	if challenge == nil {
+		return nil, fmt.Errorf("challenge source returned no challenge")
+	}
+
+	return challenge, nil
+}
+
+// Ping checks that the KeyClaim API is reachable. The health endpoint is
+// public, so no Authorization header is sent.
+func (c *KeyClaimClient) Ping() error {
+	return c.PingContext(context.Background())
+}
+
+// PingContext checks that the KeyClaim API is reachable, honoring ctx cancellation
+func (c *KeyClaimClient) PingContext(ctx context.Context) error {
+	req, err := c.newRequest(ctx, "GET", "/api/health", nil, true)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to ping: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleErrorResponse(resp, "Health check failed")
+	}
+
+	return nil
+}
+
+// SignatureInfo is the decoded form of ValidateChallengeResponse.Signature
+type SignatureInfo struct {
+	Timestamp time.Time // When the server signed the validation result
+	MAC       []byte    // HMAC-SHA256 computed by the server
+}
+
+// ParseSignature decodes the server's signature on a validation result. The
+// signature is the standard base64 encoding of an 8-byte big-endian Unix
+// timestamp in seconds followed by a 32-byte HMAC-SHA256. Other formats yield
+// an error wrapping ErrInvalidSignature.
+func (v *ValidateChallengeResponse) ParseSignature() (*SignatureInfo, error) {
+	if v.Signature == nil || *v.Signature == "" {
+		return nil, fmt.Errorf("%w: response has no signature", ErrInvalidSignature)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(*v.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not base64: %v", ErrInvalidSignature, err)
+	}
+	if len(raw) != 8+sha256.Size {
+		return nil, fmt.Errorf("%w: %d bytes, expected %d", ErrInvalidSignature, len(raw), 8+sha256.Size)
+	}
+
+	return &SignatureInfo{
+		Timestamp: time.Unix(int64(binary.BigEndian.Uint64(raw[:8])), 0),
+		MAC:       raw[8:],
+	}, nil
+}
+
+// CanCall reports whether the client is set up to make API calls: it has an
+// HTTP client and a base URL with a scheme and host. It doesn't check that
+// the API is reachable; use Ping for that.
+func (c *KeyClaimClient) CanCall() bool {
+	if c.doer == nil {
+		return false
+	}
+	u, err := url.Parse(c.baseURL)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+// IsValid checks if a validation response is valid
+func (v *ValidateChallengeResponse) IsValid() bool {
+	return v.Valid != nil && *v.Valid
+}
+
+// Result combines IsValid with the remaining quota, which is -1 when the
+// response carries no quota. When the response has an error field it is
+// returned as a *KeyClaimError, matching ErrChallengeExpired and
+// ErrQuotaExceeded like errors returned by the client.
+func (v *ValidateChallengeResponse) Result() (valid bool, remaining int, err error) {
+	remaining = -1
+	if v.Quota != nil {
+		remaining = v.Quota.Remaining
+	}
+
+	if v.Error != nil && *v.Error != "" {
+		err = &KeyClaimError{
+			Message:  *v.Error,
+			Code:     *v.Error,
+			sentinel: sentinelFor(*v.Error, 0),
+		}
+	}
+
+	return v.IsValid(), remaining, err
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying a correlation ID, which calls
+// made with it send as the X-Request-ID header
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the correlation ID stored with WithRequestID,
+// or "" if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type headersKey struct{}
+
+// WithHeaders returns a copy of ctx carrying extra headers, which calls made
+// with it send in addition to the client's own. They are merged over headers
+// from earlier WithHeaders calls and take precedence over the client's
+// defaults, except Authorization and the request signing headers. The map is
+// copied, so it may be reused or modified afterwards.
+func WithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := make(map[string]string)
+	for name, value := range headersFromContext(ctx) {
+		merged[name] = value
+	}
+	for name, value := range headers {
+		merged[name] = value
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+func headersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(headersKey{}).(map[string]string)
+	return headers
+}
+
+// ResponseMeta holds metadata about the HTTP exchange behind an API call
+type ResponseMeta struct {
+	StatusCode int
+	Header     http.Header
+	Latency    time.Duration // Total time spent, including retries and backoff
+	Attempts   int           // Number of requests sent, 1 when no retry was needed
+	RateLimit  *RateLimit    // Parsed rate-limit headers, nil when absent
+	Endpoint   string        // Base URL that answered, when fallback endpoints are tried
+	Trace      *TraceTimings // Timings of the last attempt, when Config.TraceTimings is set
+}
+
+// RateLimit holds the rate-limit state reported by the X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset response headers. Headers the
+// server doesn't send leave Limit and Remaining at -1, so that a missing
+// Remaining can't be mistaken for an exhausted one.
+type RateLimit struct {
+	Limit     int       // -1 when the limit header is absent
+	Remaining int       // -1 when the remaining header is absent
+	Reset     time.Time // Zero when the reset header is absent
+}
+
+// parseRateLimit reads the rate-limit headers from h, returning nil when none
+// are present. X-RateLimit-Reset may be a Unix timestamp or a number of
+// seconds from now; values below 1e9 are treated as the latter.
+func parseRateLimit(h http.Header, now time.Time) *RateLimit {
+	limit, limitErr := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, resetErr := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if limitErr != nil && remainingErr != nil && resetErr != nil {
+		return nil
+	}
+
+	rateLimit := &RateLimit{Limit: -1, Remaining: -1}
+	if limitErr == nil {
+		rateLimit.Limit = limit
+	}
+	if remainingErr == nil {
+		rateLimit.Remaining = remaining
+	}
+	if resetErr == nil {
+		if reset < 1e9 {
+			rateLimit.Reset = now.Add(time.Duration(reset) * time.Second)
+		} else {
+			rateLimit.Reset = time.Unix(reset, 0)
+		}
+	}
+
+	return rateLimit
+}
+
+// ErrEmptyAPIKey is returned by the constructors when no API key is given
+var ErrEmptyAPIKey = errors.New("API key is required")
+
+// ErrSecretRequired is returned by NewClientWithConfig when
+// Config.RequireExplicitSecret is set and no secret is configured
+var ErrSecretRequired = errors.New("keyclaim: an explicit secret is required")
+
+// ErrInvalidKeyPrefix is returned by the constructors when the API key doesn't
+// look like a KeyClaim key
+var ErrInvalidKeyPrefix = errors.New("invalid API key format")
+
+// ErrInvalidChallengeEncoding is returned by GenerateResponse, when
+// Config.ValidateChallengeEncoding is set, for challenges that aren't valid
+// UTF-8 or contain non-printable characters
+var ErrInvalidChallengeEncoding = errors.New("keyclaim: invalid challenge encoding")
+
+// ErrCertificatePinMismatch is returned when the server's certificate doesn't
+// match any of Config.PinnedCertSHA256
+var ErrCertificatePinMismatch = errors.New("keyclaim: server certificate doesn't match any pinned fingerprint")
+
+// ErrInsecureBaseURL is returned by NewClientWithConfig for base URLs that
+// don't use https, unless Config.RequireHTTPS is false
+var ErrInsecureBaseURL = errors.New("keyclaim: base URL must use https")
+
+// ErrInvalidSignature is returned by ValidateChallengeResponse.ParseSignature
+// for signatures that aren't in the documented format
+var ErrInvalidSignature = errors.New("keyclaim: unrecognized signature format")
+
+// ErrAmbiguousValidation is returned by ValidateChallenge when the API
+// answers 200 with neither "valid" nor "error", so that a malformed response
+// isn't mistaken for an invalid result
+var ErrAmbiguousValidation = errors.New("keyclaim: validation response has neither valid nor error")
+
+// ErrUnknownField is returned for responses with fields the SDK doesn't
+// model when Config.StrictDecode is set
+var ErrUnknownField = errors.New("keyclaim: unknown field in response")
+
+// ErrZeroTTL is returned for a zero TTL when Config.StrictTTL is set
+var ErrZeroTTL = errors.New("keyclaim: TTL must be set")
+
+// ErrValidationRejected is returned by the full-flow Validate methods when
+// the API reports a result as valid but Config.ValidationSuccessPredicate
+// rejects it
+var ErrValidationRejected = errors.New("keyclaim: validation rejected by success predicate")
+
+// ErrConcurrencyLimit is returned when Config.FailFastOnConcurrency is set and
+// all Config.MaxConcurrency request slots are in use
+var ErrConcurrencyLimit = errors.New("keyclaim: too many concurrent requests")
+
+// ErrQuotaExceeded is matched by errors.Is when the API reports that the
+// quota is exhausted, either through a "quota_exceeded" error code or an
+// HTTP 402 response
+var ErrQuotaExceeded = errors.New("keyclaim: quota exceeded")
+
+// ErrDecryptionRequired is returned by Validate when the created challenge is
+// encrypted and Config.Decryptor isn't set. Set it, or use CreateChallenge and
+// ValidateChallenge with the decrypted challenge instead.
+var ErrDecryptionRequired = errors.New("keyclaim: challenge is encrypted and no decryption is configured")
+
+// ErrValidationFailed is matched by the *ValidationFailedError returned for
+// invalid results when Config.ErrorOnInvalid is set
+var ErrValidationFailed = errors.New("keyclaim: validation failed")
+
+// ErrStaleChallenge is returned by Validate for challenges older than
+// Config.MaxChallengeAge, or of unknown age
+var ErrStaleChallenge = errors.New("keyclaim: challenge is older than the maximum age")
+
+// ErrChallengeExpired is returned by Validate and ValidateFromChallengeJSON
+// when the challenge expired before it could be validated. It is also matched
+// by errors.Is for API errors with a "challenge_expired" or "expired" code.
+var ErrChallengeExpired = errors.New("keyclaim: challenge expired before validation")
+
+// KeyClaimError represents an error from the KeyClaim API
+type KeyClaimError struct {
+	Message    string // Human-readable text: the body's "message", else its "error"
+	Code       string // Machine-readable code: the body's "error"
 	StatusCode int
+	Quota      *Quota // Quota information, if included in the error response
+	RequestID  string // X-Request-ID sent with the failed request, if any
+
+	sentinel error
 }
 
 func (e *KeyClaimError) Error() string {
 	return e.Message
 }
 
+// Unwrap returns the sentinel error matching this API error, if any
+func (e *KeyClaimError) Unwrap() error {
+	return e.sentinel
+}
+
+// ValidationFailedError is returned with the result by the validate methods
+// when Config.ErrorOnInvalid is set and the server reports the response
+// invalid. It matches ErrValidationFailed with errors.Is, as well as
+// ErrChallengeExpired and ErrQuotaExceeded when Code denotes them.
+type ValidationFailedError struct {
+	Code   string // The result's error field, if any
+	Result *ValidateChallengeResponse
+}
+
+func newValidationFailedError(result *ValidateChallengeResponse) *ValidationFailedError {
+	e := &ValidationFailedError{Result: result}
+	if result.Error != nil {
+		e.Code = *result.Error
+	}
+	return e
+}
+
+func (e *ValidationFailedError) Error() string {
+	if e.Code == "" {
+		return ErrValidationFailed.Error()
+	}
+	return ErrValidationFailed.Error() + ": " + e.Code
+}
+
+// Is reports whether target is ErrValidationFailed
+func (e *ValidationFailedError) Is(target error) bool {
+	return target == ErrValidationFailed
+}
+
+// Unwrap returns the sentinel error matching Code, if any
+func (e *ValidationFailedError) Unwrap() error {
+	return sentinelFor(e.Code, 0)
+}
+
+// FlowCanceledError is returned by the full-flow Validate methods when ctx is
+// canceled after the challenge was created, so the caller can still log or
+// clean up the challenge. Err is the underlying error, which matches
+// ctx.Err() with errors.Is.
+type FlowCanceledError struct {
+	Challenge *CreateChallengeResponse
+	Err       error
+}
+
+func (e *FlowCanceledError) Error() string {
+	return fmt.Sprintf("validation of challenge canceled: %v", e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FlowCanceledError) Unwrap() error {
+	return e.Err
+}
+
+// newRequest builds an API request for path, JSON-encoding body when non-nil.
+// The Authorization header is set unless noAuth is true, which is reserved
+// for public endpoints such as the health check.
+func (c *KeyClaimClient) newRequest(ctx context.Context, method, path string, body interface{}, noAuth bool) (*http.Request, error) {
+	return c.newRequestAt(ctx, c.baseURL, method, path, body, noAuth)
+}
+
+// orDefault returns baseURL, or fallback when baseURL is empty
+func orDefault(baseURL, fallback string) string {
+	if baseURL == "" {
+		return fallback
+	}
+	return baseURL
+}
+
+// newRequestAt builds an API request like newRequest, against baseURL
+func (c *KeyClaimClient) newRequestAt(ctx context.Context, baseURL, method, path string, body interface{}, noAuth bool) (*http.Request, error) {
+	var jsonData []byte
+	var pooledBody *requestBody
+	if body != nil {
+		var err error
+		pooledBody, jsonData, err = marshalRequestBody(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		jsonData = renameFields(jsonData, c.fieldMap)
+		if c.bodyInterceptor != nil {
+			// The marshaled body lives in a pooled buffer, reused once the
+			// request is done, so the interceptor gets its own copy
+			jsonData, err = c.bodyInterceptor(path, bytes.Clone(jsonData))
+			if err != nil {
+				pooledBody.release()
+				return nil, fmt.Errorf("body interceptor: %w", err)
+			}
+		}
+		pooledBody.setData(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, nil)
+	if err != nil {
+		if pooledBody != nil {
+			pooledBody.release()
+		}
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if pooledBody != nil {
+		// Content-Length follows the final body, after renaming and the
+		// interceptor
+		req.Body = pooledBody.reader()
+		req.GetBody = pooledBody.getBody
+		req.ContentLength = int64(len(jsonData))
+	}
+
+	req.Header.Set("Accept", c.accept)
+	if requestID := c.requestIDFromContext(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range headersFromContext(ctx) {
+		req.Header.Set(name, value)
+	}
+	if !noAuth {
+		req.Header.Set("Authorization", "Bearer "+c.currentAPIKey())
+		if c.signRequests {
+			c.signRequest(req, jsonData, time.Now())
+		}
+	}
+
+	return req, nil
+}
+
+// doWithFallback sends an authenticated request to the validate base URL,
+// which defaults to the base URL, then to each of the fallback base URLs in
+// order, until one gives a definitive answer.
+// Transport errors and 5xx responses move on to the next endpoint; the last
+// endpoint's response is returned as-is. The endpoint that answered is
+// recorded in meta.
+func (c *KeyClaimClient) doWithFallback(ctx context.Context, method, path string, body interface{}, meta *ResponseMeta) (*http.Response, error) {
+	endpoints := append([]string{orDefault(c.validateBaseURL, c.baseURL)}, c.fallbackBaseURLs...)
+
+	var lastErr error
+	for i, endpoint := range endpoints {
+		req, err := c.newRequestAt(ctx, endpoint, method, path, body, false)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := c.do(req, meta)
+		if err == nil && (resp.StatusCode < http.StatusInternalServerError || i == len(endpoints)-1) {
+			if meta != nil {
+				meta.Endpoint = endpoint
+			}
+			return resp, nil
+		}
+
+		if err == nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			err = fmt.Errorf("%s returned status %d", endpoint, resp.StatusCode)
+		} else if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, lastErr
+}
+
+// do sends req through the circuit breaker, if one is configured, and passes
+// the response to the error classifier, if one is configured
+func (c *KeyClaimClient) do(req *http.Request, meta *ResponseMeta) (*http.Response, error) {
+	return c.send(req, meta, false)
+}
+
+// send implements do. A stream request is sent without the client's timeout,
+// and a 200 response to it is handed back unread: its body isn't buffered for
+// the debug hook, the request history, status handlers or the error
+// classifier.
+func (c *KeyClaimClient) send(req *http.Request, meta *ResponseMeta, stream bool) (*http.Response, error) {
+	if body, ok := req.Body.(*bodyReader); ok {
+		defer body.owner.release()
+	}
+
+	var trial bool
+	if c.breaker != nil {
+		var err error
+		if trial, err = c.breaker.allow(); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, sent, err := c.doWithRetries(req, meta, stream)
+	if c.breaker != nil {
+		if sent {
+			c.breaker.record(req.Context(), trial, resp, err)
+		} else {
+			c.breaker.release(trial)
+		}
+	}
+	if err != nil {
+		return resp, err
+	}
+	if stream && resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+
+	if handler := c.statusHandlers[resp.StatusCode]; handler != nil {
+		if resp, err = handleStatus(resp, handler); err != nil {
+			return nil, err
+		}
+	}
+	if c.errorClassifier == nil {
+		return resp, nil
+	}
+	return c.classify(resp)
+}
+
+// handleStatus runs a status handler on resp, restoring resp.Body so it can
+// still be decoded by the caller when the handler returns nil
+func handleStatus(resp *http.Response, handler func(*http.Response) error) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := handler(resp); err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// classify runs the error classifier on resp, restoring resp.Body so it can
+// still be decoded by the caller when the classifier returns nil
+func (c *KeyClaimClient) classify(resp *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := c.errorClassifier(resp.StatusCode, body); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// doWithRetries sends req, retrying transport errors and retryable status
+// codes up to maxRetries times with exponential backoff. When meta is non-nil
+// it is populated with the outcome of the exchange. The second result reports
+// whether the outcome came from the backend, as opposed to an error raised
+// before the last attempt was sent, such as ErrConcurrencyLimit.
+func (c *KeyClaimClient) doWithRetries(req *http.Request, meta *ResponseMeta, stream bool) (*http.Response, bool, error) {
+	if c.auditHook != nil {
+		record, err := c.auditRecord(req)
+		if err != nil {
+			return nil, false, err
+		}
+		c.auditHook(record)
+	}
+
+	doer := c.doer
+	if httpClient, ok := doer.(*http.Client); ok && stream {
+		// The connection is expected to outlive the per-request timeout
+		streamClient := *httpClient
+		streamClient.Timeout = 0
+		doer = &streamClient
+	}
+
+	start := time.Now()
+	backoff := c.retryBackoff
+
+	var resp *http.Response
+	var err error
+	attempts := 0
+	for {
+		attempts++
+
+		attemptReq := req
+		if attempts > 1 && req.GetBody != nil {
+			attemptReq = req.Clone(req.Context())
+			if attemptReq.Body, err = req.GetBody(); err != nil {
+				return nil, false, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+		}
+
+		var trace *requestTrace
+		if c.traceTimings && meta != nil {
+			var ctx context.Context
+			ctx, trace = withTrace(attemptReq.Context())
+			attemptReq = attemptReq.WithContext(ctx)
+		}
+
+		if err := c.acquireSlot(req.Context()); err != nil {
+			return nil, false, err
+		}
+		resp, err = doer.Do(attemptReq)
+		c.releaseSlot()
+		if trace != nil {
+			meta.Trace = trace.result()
+		}
+
+		if attempts > c.maxRetries || !shouldRetry(resp, err) {
+			break
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		c.emit(Event{Type: EventRetryScheduled, Path: req.URL.Path, Attempt: attempts, Delay: backoff, Err: err})
+
+		select {
+		case <-req.Context().Done():
+			return nil, false, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+
+	if (c.debugHook != nil || c.history != nil) && resp != nil {
+		if err := c.tapExchange(req, resp, stream && resp.StatusCode == http.StatusOK); err != nil {
+			resp.Body.Close()
+			return nil, true, err
+		}
+	} else if c.history != nil {
+		c.history.add(RequestRecord{DebugExchange: requestExchange(req), Time: time.Now(), Error: err.Error()})
+	}
+
+	if c.logger != nil {
+		attrs := []any{"method", req.Method, "path", req.URL.Path, "attempts", attempts, "latency", time.Since(start)}
+		if requestID := req.Header.Get("X-Request-ID"); requestID != "" {
+			attrs = append(attrs, "request_id", requestID)
+		}
+		if resp != nil {
+			attrs = append(attrs, "status", resp.StatusCode)
+		} else {
+			attrs = append(attrs, "error", err)
+		}
+		if resp == nil || resp.StatusCode >= http.StatusInternalServerError {
+			c.logger.Warn("keyclaim: request failed", attrs...)
+		} else {
+			c.logger.Debug("keyclaim: request completed", attrs...)
+		}
+	}
+
+	if meta != nil {
+		meta.Latency = time.Since(start)
+		meta.Attempts = attempts
+		if resp != nil {
+			meta.StatusCode = resp.StatusCode
+			meta.Header = resp.Header
+			meta.RateLimit = parseRateLimit(resp.Header, time.Now())
+		}
+	}
+
+	return resp, true, err
+}
+
+// DebugExchange holds a raw request/response pair captured for Config.DebugHook
+type DebugExchange struct {
+	Method         string
+	URL            string
+	RequestHeader  http.Header // Authorization is redacted
+	RequestBody    []byte
+	StatusCode     int
+	ResponseHeader http.Header
+	ResponseBody   []byte
+}
+
+// tapExchange passes copies of the request and response bodies to the debug
+// hook and the request history, restoring resp.Body so it can still be
+// decoded by the caller. The response body of a stream is left unread and
+// isn't captured.
+func (c *KeyClaimClient) tapExchange(req *http.Request, resp *http.Response, stream bool) error {
+	exchange := requestExchange(req)
+	exchange.StatusCode = resp.StatusCode
+	exchange.ResponseHeader = resp.Header.Clone()
+
+	if !stream {
+		responseBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+		exchange.ResponseBody = responseBody
+	}
+
+	if c.debugHook != nil {
+		c.debugHook(exchange)
+	}
+	if c.history != nil {
+		c.history.add(RequestRecord{DebugExchange: exchange, Time: time.Now()})
+	}
+	return nil
+}
+
+// requestExchange captures the request half of an exchange, with the
+// Authorization header redacted
+func requestExchange(req *http.Request) DebugExchange {
+	exchange := DebugExchange{
+		Method:        req.Method,
+		URL:           req.URL.String(),
+		RequestHeader: req.Header.Clone(),
+	}
+	if exchange.RequestHeader.Get("Authorization") != "" {
+		exchange.RequestHeader.Set("Authorization", "[REDACTED]")
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			exchange.RequestBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	return exchange
+}
+
+// checkContentType returns an error unless resp declares a JSON body. A
+// missing Content-Type is tolerated.
+func checkContentType(resp *http.Response) error {
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")) {
+		return nil
+	}
+
+	return fmt.Errorf("unexpected response content type %q, expected JSON", contentType)
+}
+
+// acquireSlot takes one of the MaxConcurrency request slots, waiting for one
+// to free up unless FailFastOnConcurrency is set
+func (c *KeyClaimClient) acquireSlot(ctx context.Context) error {
+	if c.slots == nil {
+		return nil
+	}
+
+	if c.failFastOnConcurrency {
+		select {
+		case c.slots <- struct{}{}:
+			return nil
+		default:
+			return ErrConcurrencyLimit
+		}
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSlot frees a slot taken by acquireSlot
+func (c *KeyClaimClient) releaseSlot() {
+	if c.slots != nil {
+		<-c.slots
+	}
+}
+
+// shouldRetry reports whether a request that produced resp and err is worth retrying
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+}
+
 func (c *KeyClaimClient) handleErrorResponse(resp *http.Response, defaultMessage string) error {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return &KeyClaimError{
+		return withRequestID(&KeyClaimError{
 			Message:    defaultMessage,
 			StatusCode: resp.StatusCode,
-		}
+		}, resp)
 	}
-	return c.handleErrorResponseFromBody(bodyBytes, resp.StatusCode, defaultMessage)
+	bodyBytes = renameFields(bodyBytes, c.responseFieldMap)
+	return withRequestID(c.handleErrorResponseFromBody(bodyBytes, resp.StatusCode, defaultMessage), resp)
+}
+
+// withRequestID records the X-Request-ID sent with resp's request on err, if
+// it is a KeyClaimError
+func withRequestID(err error, resp *http.Response) error {
+	var keyClaimErr *KeyClaimError
+	if errors.As(err, &keyClaimErr) && resp.Request != nil {
+		keyClaimErr.RequestID = resp.Request.Header.Get("X-Request-ID")
+	}
+	return err
 }
 
 func (c *KeyClaimClient) handleErrorResponseFromBody(bodyBytes []byte, statusCode int, defaultMessage string) error {
@@ -307,27 +2604,156 @@ func (c *KeyClaimClient) handleErrorResponseFromBody(bodyBytes []byte, statusCod
 		}
 	}
 
-	var errorMessage string
-	var errorCode string
-
-	if err, ok := errorData["error"].(string); ok {
-		errorMessage = err
-		errorCode = err
-	} else if msg, ok := errorData["message"].(string); ok {
-		errorMessage = msg
+	// "error" carries the machine-readable code and "message" the human
+	// readable text. When only "error" is present it doubles as the message.
+	// Some gateways nest both in an "error" object instead.
+	var errorCode, errorMessage string
+	if nested, ok := errorData["error"].(map[string]interface{}); ok {
+		errorCode = errorString(nested["code"])
+		errorMessage = errorString(nested["message"])
 	} else {
+		errorCode = errorString(errorData["error"])
+	}
+	if errorMessage == "" {
+		errorMessage = errorString(errorData["message"])
+	}
+	if errorMessage == "" {
+		errorMessage = errorCode
+	}
+	if errorMessage == "" {
 		errorMessage = defaultMessage
 	}
 
-	return &KeyClaimError{
+	keyClaimErr := &KeyClaimError{
 		Message:    errorMessage,
 		Code:       errorCode,
 		StatusCode: statusCode,
 	}
+
+	var quotaData struct {
+		Quota *Quota `json:"quota"`
+	}
+	if err := json.Unmarshal(bodyBytes, &quotaData); err == nil {
+		keyClaimErr.Quota = quotaData.Quota
+	}
+
+	keyClaimErr.sentinel = sentinelFor(errorCode, statusCode)
+
+	return keyClaimErr
+}
+
+// errorString coerces a decoded JSON error field to a string. Numbers, as
+// used by some gateways for error codes, are formatted without an exponent;
+// other types yield "".
+func errorString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// sentinelFor returns the sentinel error matched by a KeyClaimError with the
+// given code and status, or nil
+func sentinelFor(code string, statusCode int) error {
+	if strings.EqualFold(code, "quota_exceeded") || statusCode == http.StatusPaymentRequired {
+		return ErrQuotaExceeded
+	}
+	if isExpiredCode(code) {
+		return ErrChallengeExpired
+	}
+	if isReplayCode(code) {
+		return ErrReplayDetected
+	}
+	return nil
+}
+
+// renameFields returns body with its top-level fields renamed according to
+// names. A renamed field replaces any field already using its new name, so
+// that e.g. ValidateChallengeOptions.Metadata can't take the place of a
+// renamed core field. The body is returned unchanged when names is empty, the
+// body isn't a JSON object, or none of its fields are renamed.
+func renameFields(body []byte, names map[string]string) []byte {
+	if len(names) == 0 {
+		return body
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return body
+	}
+
+	renamed := make(map[string]json.RawMessage, len(object))
+	changed := false
+	for field, value := range object {
+		if _, ok := names[field]; ok {
+			changed = true
+			continue
+		}
+		renamed[field] = value
+	}
+	if !changed {
+		return body
+	}
+	for field, value := range object {
+		if name, ok := names[field]; ok {
+			renamed[name] = value
+		}
+	}
+
+	result, err := json.Marshal(renamed)
+	if err != nil {
+		return body
+	}
+	return result
+}
+
+// invertFieldMap returns the response renames for Config.FieldMap, mapping
+// the server's names back to the SDK's
+func invertFieldMap(fieldMap map[string]string) map[string]string {
+	if len(fieldMap) == 0 {
+		return nil
+	}
+
+	inverted := make(map[string]string, len(fieldMap))
+	for sdkName, serverName := range fieldMap {
+		inverted[serverName] = sdkName
+	}
+	return inverted
+}
+
+// unwrapDataField returns the contents of a top-level "data" object when none
+// of the given fields are present at the top level. Otherwise, or when the
+// body isn't a JSON object, the body is returned unchanged.
+func unwrapDataField(body []byte, fields ...string) []byte {
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return body
+	}
+
+	for _, field := range fields {
+		if _, ok := envelope[field]; ok {
+			return body
+		}
+	}
+
+	data, ok := envelope["data"]
+	if !ok {
+		return body
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil || object == nil {
+		return body
+	}
+
+	return data
 }
 
 // Helper function to check prefix (for Go 1.20 compatibility)
 func hasPrefix(s, prefix string) bool {
 	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
 }
-
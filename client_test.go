@@ -1,10 +1,25 @@
 package keyclaim
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestNewClient(t *testing.T) {
@@ -22,6 +37,12 @@ func TestNewClient_InvalidAPIKey(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for invalid API key")
 	}
+	if !errors.Is(err, ErrInvalidKeyPrefix) || errors.Is(err, ErrEmptyAPIKey) {
+		t.Errorf("Expected ErrInvalidKeyPrefix, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "kc_") {
+		t.Errorf("Expected error message to mention the kc_ prefix, got %v", err)
+	}
 }
 
 func TestNewClient_EmptyAPIKey(t *testing.T) {
@@ -29,6 +50,72 @@ func TestNewClient_EmptyAPIKey(t *testing.T) {
 	if err == nil {
 		t.Fatal("Expected error for empty API key")
 	}
+	if !errors.Is(err, ErrEmptyAPIKey) || errors.Is(err, ErrInvalidKeyPrefix) {
+		t.Errorf("Expected ErrEmptyAPIKey, got %v", err)
+	}
+}
+
+func TestNewClient_DefaultBaseURL(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	if client.baseURL != "https://keyclaim.org" {
+		t.Errorf("Expected built-in base URL, got %s", client.baseURL)
+	}
+
+	DefaultBaseURL = "https://auth.example.com"
+	defer func() { DefaultBaseURL = "" }()
+
+	client, _ = NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	if client.baseURL != "https://auth.example.com" {
+		t.Errorf("Expected overridden base URL, got %s", client.baseURL)
+	}
+}
+
+func TestNewClientWithConfig_BaseURL(t *testing.T) {
+	client, err := NewClientWithConfig(Config{
+		APIKey:  "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL: "https://auth.example.com",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if client.baseURL != "https://auth.example.com" {
+		t.Errorf("Expected configured base URL, got %s", client.baseURL)
+	}
+}
+
+func TestNewClientWithConfig_RequireHTTPS(t *testing.T) {
+	_, err := NewClientWithConfig(Config{
+		APIKey:  "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL: "http://auth.example.com",
+	})
+	if !errors.Is(err, ErrInsecureBaseURL) {
+		t.Fatalf("Expected ErrInsecureBaseURL, got %v", err)
+	}
+
+	_, err = NewClientWithConfig(Config{
+		APIKey:           "kc_test123456789012345678901234567890123456789012345678901234567890",
+		FallbackBaseURLs: []string{"http://backup.example.com"},
+	})
+	if !errors.Is(err, ErrInsecureBaseURL) {
+		t.Fatalf("Expected ErrInsecureBaseURL for a fallback, got %v", err)
+	}
+
+	var logs bytes.Buffer
+	client, err := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL:      "http://localhost:8080",
+		RequireHTTPS: boolPtr(false),
+		Logger:       slog.New(slog.NewTextHandler(&logs, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Expected http to be allowed when RequireHTTPS is false, got %v", err)
+	}
+	if client.baseURL != "http://localhost:8080" {
+		t.Errorf("Expected configured base URL, got %s", client.baseURL)
+	}
+	if !strings.Contains(logs.String(), "level=WARN") {
+		t.Errorf("Expected a warning to be logged, got %q", logs.String())
+	}
 }
 
 func TestCreateChallenge(t *testing.T) {
@@ -62,6 +149,69 @@ func TestCreateChallenge(t *testing.T) {
 	}
 }
 
+func TestCreateChallenge_InsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := CreateChallengeResponse{
+			Challenge: "test-challenge-123",
+			ExpiresIn: 30,
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:             "kc_test123456789012345678901234567890123456789012345678901234567890",
+		InsecureSkipVerify: true,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error with InsecureSkipVerify, got %v", err)
+	}
+
+	client, _ = NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err == nil {
+		t.Fatal("Expected certificate verification error without InsecureSkipVerify")
+	}
+}
+
+func TestCreateChallenge_PinnedCertSHA256(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	fingerprint := sha256.Sum256(server.Certificate().Raw)
+	pinnedClient := func(pin string) *KeyClaimClient {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:           "kc_test123456789012345678901234567890123456789012345678901234567890",
+			PinnedCertSHA256: []string{pin},
+		})
+		client.baseURL = server.URL
+
+		// Trust the test server's self-signed certificate, so that only the
+		// pin decides
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+		client.client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+		return client
+	}
+
+	client := pinnedClient(strings.ToUpper(hex.EncodeToString(fingerprint[:])))
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error with the matching pin, got %v", err)
+	}
+
+	client = pinnedClient(strings.Repeat("00", sha256.Size))
+	if _, err := client.CreateChallenge(30); !errors.Is(err, ErrCertificatePinMismatch) {
+		t.Fatalf("Expected ErrCertificatePinMismatch with a wrong pin, got %v", err)
+	}
+}
+
 func TestGenerateResponse_Echo(t *testing.T) {
 	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
 	
@@ -124,6 +274,185 @@ func TestGenerateResponse_Custom_NoData(t *testing.T) {
 	}
 }
 
+func TestPreSign(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	client.baseURL = "http://127.0.0.1:0" // Offline: any request would fail
+
+	challenges := []string{"test-challenge", "a1b2c3d4e5f6", ""}
+	signed, err := client.PreSign(challenges, ResponseMethodHMAC)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(signed) != len(challenges) {
+		t.Fatalf("Expected %d signed challenges, got %d", len(challenges), len(signed))
+	}
+
+	for i, s := range signed {
+		if s.Challenge != challenges[i] || s.Method != ResponseMethodHMAC {
+			t.Errorf("Unexpected signed challenge %d: %+v", i, s)
+		}
+		if ok, err := client.VerifyResponse(s.Challenge, s.Response, s.Method, nil); err != nil || !ok {
+			t.Errorf("Expected a valid hmac response for %q", s.Challenge)
+		}
+	}
+	if signed[0].Response != "115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42" {
+		t.Errorf("Expected the test vector response, got %s", signed[0].Response)
+	}
+
+	if _, err := client.PreSign(challenges, ResponseMethod("unknown")); err == nil {
+		t.Error("Expected error for an unknown method")
+	}
+}
+
+func TestGenerateAllResponses(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	responses, err := client.GenerateAllResponses("test-challenge", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(responses) != 3 {
+		t.Errorf("Expected 3 responses, got %d", len(responses))
+	}
+	if responses[ResponseMethodEcho] != "test-challenge" {
+		t.Errorf("Expected echo response 'test-challenge', got %s", responses[ResponseMethodEcho])
+	}
+	for _, method := range []ResponseMethod{ResponseMethodHMAC, ResponseMethodHash} {
+		if _, err := hex.DecodeString(responses[method]); err != nil || len(responses[method]) != 64 {
+			t.Errorf("Expected 64 hex chars for %s, got %q", method, responses[method])
+		}
+	}
+	if _, ok := responses[ResponseMethodCustom]; ok {
+		t.Error("Expected no custom response without custom data")
+	}
+
+	responses, err = client.GenerateAllResponses("test-challenge", "custom-string")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(responses[ResponseMethodCustom]) != 64 {
+		t.Errorf("Expected custom hash length 64, got %d", len(responses[ResponseMethodCustom]))
+	}
+}
+
+func TestVerifyResponse(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	other, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "other-secret")
+
+	for _, method := range []ResponseMethod{ResponseMethodEcho, ResponseMethodHMAC, ResponseMethodHash} {
+		response, _ := client.GenerateResponse("test-challenge", method, nil)
+
+		ok, err := client.VerifyResponse("test-challenge", response, method, nil)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", method, err)
+		}
+		if !ok {
+			t.Errorf("Expected %s response to verify", method)
+		}
+
+		ok, _ = client.VerifyResponse("other-challenge", response, method, nil)
+		if ok {
+			t.Errorf("Expected %s response not to verify for a different challenge", method)
+		}
+
+		if method != ResponseMethodEcho {
+			ok, _ = other.VerifyResponse("test-challenge", response, method, nil)
+			if ok {
+				t.Errorf("Expected %s response not to verify with a different secret", method)
+			}
+		}
+	}
+}
+
+func TestVerifyResponse_UnknownMethod(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	if _, err := client.VerifyResponse("test-challenge", "test-response", ResponseMethod("bogus"), nil); err == nil {
+		t.Fatal("Expected error for unknown method")
+	}
+}
+
+func TestValidateLocally(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	customData := map[string]interface{}{"user_id": 123}
+
+	for _, method := range []ResponseMethod{ResponseMethodEcho, ResponseMethodHMAC, ResponseMethodHash, ResponseMethodCustom} {
+		response, _ := client.GenerateResponse("test-challenge", method, customData)
+
+		valid, err := client.ValidateLocally("test-challenge", response, method, customData)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", method, err)
+		}
+		if !valid {
+			t.Errorf("Expected %s response to be valid", method)
+		}
+
+		for _, invalid := range []string{"", "wrong-response", response + "0"} {
+			valid, err := client.ValidateLocally("test-challenge", invalid, method, customData)
+			if err != nil {
+				t.Fatalf("Expected no error for invalid %s response %q, got %v", method, invalid, err)
+			}
+			if valid {
+				t.Errorf("Expected %s response %q to be invalid", method, invalid)
+			}
+		}
+	}
+
+	if _, err := client.ValidateLocally("test-challenge", "test-response", ResponseMethod("bogus"), nil); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+}
+
+func TestGenerateProof(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	echo, proof, err := client.GenerateProof("test-challenge")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if echo != "test-challenge" {
+		t.Errorf("Expected echo 'test-challenge', got %s", echo)
+	}
+	if len(proof) != 64 {
+		t.Errorf("Expected HMAC length 64, got %d", len(proof))
+	}
+	if _, err := hex.DecodeString(proof); err != nil {
+		t.Errorf("Expected hex HMAC, got %s", proof)
+	}
+
+	expected, _ := client.GenerateResponse("test-challenge", ResponseMethodHMAC, nil)
+	if proof != expected {
+		t.Errorf("Expected HMAC %s, got %s", expected, proof)
+	}
+}
+
+func TestGenerateResponse_DefaultCustomData(t *testing.T) {
+	client, _ := NewClientWithConfig(Config{
+		APIKey:            "kc_test123456789012345678901234567890123456789012345678901234567890",
+		DefaultCustomData: "app-123",
+	})
+	plain, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	response, err := client.GenerateResponse("test-challenge", ResponseMethodCustom, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected, _ := plain.GenerateResponse("test-challenge", ResponseMethodCustom, "app-123")
+	if response != expected {
+		t.Errorf("Expected the default custom data to be used, got %s", response)
+	}
+
+	response, _ = client.GenerateResponse("test-challenge", ResponseMethodCustom, "per-call")
+	expected, _ = plain.GenerateResponse("test-challenge", ResponseMethodCustom, "per-call")
+	if response != expected {
+		t.Errorf("Expected per-call custom data to take precedence, got %s", response)
+	}
+
+	if _, err := plain.GenerateResponse("test-challenge", ResponseMethodCustom, nil); err == nil {
+		t.Error("Expected error without per-call or default custom data")
+	}
+}
+
 func TestValidateChallenge(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		response := ValidateChallengeResponse{
@@ -153,12 +482,12 @@ func TestValidateChallenge(t *testing.T) {
 
 func TestValidateChallenge_Invalid(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusBadRequest)
 		response := ValidateChallengeResponse{
 			Valid: boolPtr(false),
 			Error:  stringPtr("Invalid response"),
 		}
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 	}))
 	defer server.Close()
@@ -175,32 +504,240 @@ func TestValidateChallenge_Invalid(t *testing.T) {
 	}
 }
 
-func TestValidate(t *testing.T) {
-	createServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := CreateChallengeResponse{
-			Challenge: "test-challenge-123",
-			ExpiresIn: 30,
-		}
+func TestValidateChallenge_UnwrapData(t *testing.T) {
+	bodies := map[string]string{
+		"wrapped":   `{"data": {"valid": true, "quota": {"used": 1, "remaining": 9, "quota": 10}}}`,
+		"unwrapped": `{"valid": true, "quota": {"used": 1, "remaining": 9, "quota": 10}}`,
+	}
+
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(body))
+			}))
+			defer server.Close()
+
+			client, _ := NewClientWithConfig(Config{
+				APIKey:     "kc_test123456789012345678901234567890123456789012345678901234567890",
+				UnwrapData: true,
+			})
+			client.baseURL = server.URL
+
+			result, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !result.IsValid() {
+				t.Error("Expected validation to be valid")
+			}
+			if result.Quota == nil || result.Quota.Remaining != 9 {
+				t.Errorf("Expected quota remaining 9, got %+v", result.Quota)
+			}
+		})
+	}
+}
+
+func TestValidateChallenge_WrappedWithoutUnwrapData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		w.Write([]byte(`{"data": {"valid": true}}`))
 	}))
-	defer createServer.Close()
+	defer server.Close()
 
-	validateServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		response := ValidateChallengeResponse{
-			Valid: boolPtr(true),
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	// Left alone, the wrapped body has neither top-level field
+	result, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if !errors.Is(err, ErrAmbiguousValidation) {
+		t.Fatalf("Expected ErrAmbiguousValidation without UnwrapData, got %v", err)
+	}
+	if result != nil {
+		t.Error("Expected no result")
+	}
+}
+
+func TestValidateChallenge_Ambiguous(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	result, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if !errors.Is(err, ErrAmbiguousValidation) {
+		t.Fatalf("Expected ErrAmbiguousValidation, got %v", err)
+	}
+	if result != nil {
+		t.Error("Expected no result")
+	}
+}
+
+func TestValidateChallenge_NoContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	result, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected a 204 to be treated as valid")
+	}
+}
+
+func TestValidateChallenge_ErrorOnInvalid(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch body.Response {
+		case "good":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		case "expired":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("challenge_expired")})
+		default:
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("invalid_response")})
+		}
+	}))
+	defer server.Close()
+
+	t.Run("off", func(t *testing.T) {
+		client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+		client.baseURL = server.URL
+
+		result, err := client.ValidateChallenge("test-challenge", "bad", nil)
+		if err != nil {
+			t.Fatalf("Expected no error by default, got %v", err)
+		}
+		if result.IsValid() {
+			t.Error("Expected an invalid result")
+		}
+	})
+
+	t.Run("on", func(t *testing.T) {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+			ErrorOnInvalid: true,
+		})
+		client.baseURL = server.URL
+
+		result, err := client.ValidateChallenge("test-challenge", "bad", nil)
+		if !errors.Is(err, ErrValidationFailed) {
+			t.Fatalf("Expected ErrValidationFailed, got %v", err)
+		}
+		var failedErr *ValidationFailedError
+		if !errors.As(err, &failedErr) || failedErr.Code != "invalid_response" || failedErr.Result != result {
+			t.Errorf("Expected a *ValidationFailedError carrying the server's error, got %+v", err)
+		}
+		if result == nil || result.IsValid() {
+			t.Error("Expected the invalid result to be returned too")
+		}
+		if !strings.Contains(err.Error(), "invalid_response") {
+			t.Errorf("Expected the server's error in the message, got %q", err.Error())
+		}
+
+		_, err = client.ValidateChallenge("test-challenge", "expired", nil)
+		if !errors.Is(err, ErrValidationFailed) || !errors.Is(err, ErrChallengeExpired) {
+			t.Errorf("Expected ErrValidationFailed and ErrChallengeExpired, got %v", err)
+		}
+
+		if _, err := client.ValidateChallenge("test-challenge", "good", nil); err != nil {
+			t.Errorf("Expected no error for a valid result, got %v", err)
 		}
+	})
+}
+
+func TestValidateHinted(t *testing.T) {
+	tests := []struct {
+		name     string
+		hint     ResponseMethod
+		expected ResponseMethod
+	}{
+		{"hinted", ResponseMethodHash, ResponseMethodHash},
+		{"no hint", "", ResponseMethodHMAC},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validateReq ValidateChallengeOptions
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch r.URL.Path {
+				case "/api/challenge/create":
+					json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30, Method: tt.hint})
+				case "/api/challenge/validate":
+					json.NewDecoder(r.Body).Decode(&validateReq)
+					json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+				}
+			}))
+			defer server.Close()
+
+			client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+			client.baseURL = server.URL
+
+			result, err := client.ValidateHinted(30, nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if !result.IsValid() {
+				t.Error("Expected validation to be valid")
+			}
+
+			expected, _ := client.GenerateResponse("test-challenge-123", tt.expected, nil)
+			if validateReq.Response != expected {
+				t.Errorf("Expected the %s response %s, got %s", tt.expected, expected, validateReq.Response)
+			}
+		})
+	}
+
+	// Only ValidateHinted follows the hint: other flows still need a method
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30, Method: ResponseMethodHash})
 	}))
-	defer validateServer.Close()
+	defer server.Close()
 
 	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
-	client.baseURL = createServer.URL
+	client.baseURL = server.URL
+
+	if _, err := client.Validate("", 30, nil); err == nil || !strings.Contains(err.Error(), "unknown response method") {
+		t.Errorf("Expected an unknown response method error from Validate, got %v", err)
+	}
+	if _, err := client.IssueToken("", 30); err == nil || !strings.Contains(err.Error(), "unknown response method") {
+		t.Errorf("Expected an unknown response method error from IssueToken, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{
+				Challenge: "test-challenge-123",
+				ExpiresIn: 30,
+			})
+		case "/api/challenge/validate":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{
+				Valid: boolPtr(true),
+			})
+		}
+	}))
+	defer server.Close()
 
-	// Override baseURL for validation (in real usage, both would use the same baseURL)
-	originalBaseURL := client.baseURL
-	client.baseURL = validateServer.URL
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
 
 	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
 	if err != nil {
@@ -209,8 +746,27 @@ func TestValidate(t *testing.T) {
 	if !result.IsValid() {
 		t.Error("Expected validation to be valid")
 	}
+}
+
+func TestCanCall(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	if !client.CanCall() {
+		t.Error("Expected a client from NewClient to be able to call the API")
+	}
 
-	client.baseURL = originalBaseURL
+	// The SDK has no verifier-only or dry-run mode yet, so there are no
+	// cases for them; clients missing a base URL or a transport stand in for
+	// clients that can't reach the API
+	noBaseURL, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	noBaseURL.baseURL = ""
+	if noBaseURL.CanCall() {
+		t.Error("Expected a client without a base URL not to be able to call the API")
+	}
+
+	noTransport := &KeyClaimClient{baseURL: "https://keyclaim.org"}
+	if noTransport.CanCall() {
+		t.Error("Expected a client without an HTTP client not to be able to call the API")
+	}
 }
 
 // Helper functions
@@ -222,3 +778,2478 @@ func stringPtr(s string) *string {
 	return &s
 }
 
+
+func TestPing_NoAuthHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		switch r.URL.Path {
+		case "/api/health":
+			if auth != "" {
+				t.Errorf("Expected no Authorization header for health check, got %s", auth)
+			}
+			w.WriteHeader(http.StatusOK)
+		case "/api/challenge/create":
+			if auth != "Bearer kc_test123456789012345678901234567890123456789012345678901234567890" {
+				t.Errorf("Expected Authorization header for create, got %q", auth)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if err := client.Ping(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidate_ChallengeSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/challenge/validate" {
+			t.Errorf("Expected only the validate endpoint to be called, got %s", r.URL.Path)
+		}
+
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Challenge != "stub-challenge" {
+			t.Errorf("Expected challenge 'stub-challenge', got %s", body.Challenge)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	var sourceTTL int
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ChallengeSource: func(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+			sourceTTL = ttl
+			return &CreateChallengeResponse{Challenge: "stub-challenge", ExpiresIn: ttl}, nil
+		},
+	})
+	client.baseURL = server.URL
+
+	result, err := client.Validate(ResponseMethodEcho, 45, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+	if sourceTTL != 45 {
+		t.Errorf("Expected source to receive ttl 45, got %d", sourceTTL)
+	}
+}
+
+func TestCreateChallengeWithMeta_Retry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	})
+	client.baseURL = server.URL
+
+	challenge, meta, err := client.CreateChallengeWithMeta(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.Challenge != "test-challenge-123" {
+		t.Errorf("Expected challenge 'test-challenge-123', got %s", challenge.Challenge)
+	}
+	if meta.Attempts != 2 {
+		t.Errorf("Expected 2 attempts, got %d", meta.Attempts)
+	}
+	if meta.Latency <= 0 {
+		t.Errorf("Expected non-zero latency, got %v", meta.Latency)
+	}
+	if meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", meta.StatusCode)
+	}
+}
+
+func TestCreateChallenge_RetriesExhausted(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxRetries:   2,
+		RetryBackoff: time.Millisecond,
+	})
+	client.baseURL = server.URL
+
+	_, err := client.CreateChallenge(30)
+	if err == nil {
+		t.Fatal("Expected error after retries are exhausted")
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", requests)
+	}
+}
+
+func TestCreateChallenge_AcceptHeader(t *testing.T) {
+	var accept string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accept = r.Header.Get("Accept")
+		w.Header().Set("Content-Type", "application/vnd.keyclaim+json; charset=utf-8")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if accept != "application/json" {
+		t.Errorf("Expected default Accept 'application/json', got %q", accept)
+	}
+
+	client, _ = NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Accept: "application/vnd.keyclaim+json",
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if accept != "application/vnd.keyclaim+json" {
+		t.Errorf("Expected overridden Accept, got %q", accept)
+	}
+}
+
+func TestCreateChallenge_WrongContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.CreateChallenge(30)
+	if err == nil {
+		t.Fatal("Expected error for text/html response")
+	}
+	if !strings.Contains(err.Error(), "text/html") {
+		t.Errorf("Expected error to mention the content type, got %v", err)
+	}
+}
+
+func TestValidateChallenge_WrongContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Welcome</body></html>"))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if err == nil {
+		t.Fatal("Expected error for text/html response")
+	}
+	if !strings.Contains(err.Error(), "text/html") {
+		t.Errorf("Expected error to mention the content type, got %v", err)
+	}
+}
+
+func TestDebugHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"challenge":"test-challenge-123","expires_in":30}`))
+	}))
+	defer server.Close()
+
+	var exchanges []DebugExchange
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		DebugHook: func(exchange DebugExchange) {
+			exchanges = append(exchanges, exchange)
+		},
+	})
+	client.baseURL = server.URL
+
+	challenge, err := client.CreateChallenge(45)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.Challenge != "test-challenge-123" {
+		t.Errorf("Expected response to still decode after tapping, got %q", challenge.Challenge)
+	}
+
+	if len(exchanges) != 1 {
+		t.Fatalf("Expected 1 exchange, got %d", len(exchanges))
+	}
+	exchange := exchanges[0]
+	if string(exchange.RequestBody) != `{"ttl":45}` {
+		t.Errorf("Expected request body {\"ttl\":45}, got %s", exchange.RequestBody)
+	}
+	if string(exchange.ResponseBody) != `{"challenge":"test-challenge-123","expires_in":30}` {
+		t.Errorf("Unexpected response body %s", exchange.ResponseBody)
+	}
+	if auth := exchange.RequestHeader.Get("Authorization"); strings.Contains(auth, "kc_") {
+		t.Errorf("Expected Authorization to be redacted, got %s", auth)
+	}
+	if exchange.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", exchange.StatusCode)
+	}
+}
+
+func TestValidateChallenge_QuotaExceeded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":"quota_exceeded","quota":{"used":100,"remaining":0,"quota":100,"reset_at":"2026-11-01T00:00:00Z"}}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	var keyClaimErr *KeyClaimError
+	if !errors.As(err, &keyClaimErr) {
+		t.Fatalf("Expected *KeyClaimError, got %T", err)
+	}
+	if keyClaimErr.Quota == nil || keyClaimErr.Quota.Remaining != 0 {
+		t.Fatalf("Expected quota on error, got %+v", keyClaimErr.Quota)
+	}
+	expectedReset := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	if keyClaimErr.Quota.ResetAt == nil || !keyClaimErr.Quota.ResetAt.Equal(expectedReset) {
+		t.Errorf("Expected reset at %v, got %v", expectedReset, keyClaimErr.Quota.ResetAt)
+	}
+}
+
+func TestValidateChallenge_OtherErrorIsNotQuotaExceeded(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid_api_key"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if err == nil || errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected a non-quota error, got %v", err)
+	}
+}
+
+func TestValidate_EncryptedChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/challenge/validate" {
+			t.Error("Expected validate not to be called for an encrypted challenge")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{
+			Challenge: "encrypted-challenge",
+			ExpiresIn: 30,
+			Encrypted: boolPtr(true),
+		})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if !errors.Is(err, ErrDecryptionRequired) {
+		t.Fatalf("Expected ErrDecryptionRequired, got %v", err)
+	}
+
+	challenge, err := client.CreateChallenge(30)
+	if err != nil {
+		t.Fatalf("Expected CreateChallenge to return the encrypted challenge, got %v", err)
+	}
+	if challenge.Encrypted == nil || !*challenge.Encrypted {
+		t.Error("Expected Encrypted to be true")
+	}
+}
+
+func TestValidate_Decryptor(t *testing.T) {
+	var validateReq ValidateChallengeOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{
+				Challenge: "encrypted-challenge",
+				ExpiresIn: 30,
+				Encrypted: boolPtr(true),
+			})
+		case "/api/challenge/validate":
+			json.NewDecoder(r.Body).Decode(&validateReq)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Decryptor: func(ctx context.Context, encrypted string) (string, error) {
+			return strings.ToUpper(encrypted), nil
+		},
+	})
+	client.baseURL = server.URL
+
+	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+
+	if validateReq.Challenge != "encrypted-challenge" {
+		t.Errorf("Expected the encrypted challenge to be sent, got %s", validateReq.Challenge)
+	}
+	if validateReq.DecryptedChallenge == nil || *validateReq.DecryptedChallenge != "ENCRYPTED-CHALLENGE" {
+		t.Errorf("Expected decryptedChallenge ENCRYPTED-CHALLENGE, got %v", validateReq.DecryptedChallenge)
+	}
+	expected, _ := client.GenerateResponse("ENCRYPTED-CHALLENGE", ResponseMethodHMAC, nil)
+	if validateReq.Response != expected {
+		t.Errorf("Expected the response over the decrypted challenge, got %s", validateReq.Response)
+	}
+}
+
+func TestValidate_DecryptorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/challenge/validate" {
+			t.Error("Expected validate not to be called when decryption fails")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{
+			Challenge: "encrypted-challenge",
+			ExpiresIn: 30,
+			Encrypted: boolPtr(true),
+		})
+	}))
+	defer server.Close()
+
+	kmsErr := errors.New("kms unavailable")
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Decryptor: func(ctx context.Context, encrypted string) (string, error) {
+			return "", kmsErr
+		},
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); !errors.Is(err, kmsErr) {
+		t.Fatalf("Expected the decryptor's error, got %v", err)
+	}
+}
+
+func TestGenerateResponse_CustomDataValidator(t *testing.T) {
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CustomDataValidator: func(customData interface{}) error {
+			if m, ok := customData.(map[string]interface{}); ok && len(m) == 0 {
+				return errors.New("custom data must not be empty")
+			}
+			return nil
+		},
+	})
+
+	_, err := client.GenerateResponse("test-challenge", ResponseMethodCustom, map[string]interface{}{})
+	if err == nil {
+		t.Fatal("Expected error for empty custom data")
+	}
+
+	response, err := client.GenerateResponse("test-challenge", ResponseMethodCustom, map[string]interface{}{"userId": "123"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(response) != 64 {
+		t.Errorf("Expected hash length 64, got %d", len(response))
+	}
+}
+
+func TestQuota_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		used      int
+		remaining int
+		wantErr   bool
+	}{
+		{"integers", `{"used": 10, "remaining": 90, "quota": 100}`, 10, 90, false},
+		{"strings", `{"used": "10", "remaining": " 90 ", "quota": 100}`, 10, 90, false},
+		{"integral floats", `{"used": 10.0, "remaining": "9e1"}`, 10, 90, false},
+		{"missing and null", `{"used": null}`, 0, 0, false},
+		{"non-numeric string", `{"used": "ten", "remaining": 90}`, 0, 0, true},
+		{"fractional", `{"used": 10.5, "remaining": 90}`, 0, 0, true},
+		{"empty string", `{"used": 10, "remaining": ""}`, 0, 0, true},
+		{"boolean", `{"used": true, "remaining": 90}`, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quota Quota
+			err := json.Unmarshal([]byte(tt.body), &quota)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", quota)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if quota.Used != tt.used || quota.Remaining != tt.remaining {
+				t.Errorf("Expected used=%d remaining=%d, got %+v", tt.used, tt.remaining, quota)
+			}
+		})
+	}
+
+	// The other fields still decode as usual
+	var quota Quota
+	json.Unmarshal([]byte(`{"used": "10", "remaining": "90", "quota": "unlimited", "reset_at": "2026-01-01T00:00:00Z"}`), &quota)
+	if quota.Quota != "unlimited" || quota.ResetAt == nil || quota.ResetAt.Year() != 2026 {
+		t.Errorf("Expected the remaining fields to decode, got %+v", quota)
+	}
+}
+
+func TestValidateChallenge_StringQuota(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"valid": true, "quota": {"used": "10", "remaining": "90", "quota": 100}}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	result, err := client.ValidateChallenge("test-challenge", "test-response", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Quota == nil || result.Quota.Used != 10 || result.Quota.Remaining != 90 {
+		t.Errorf("Expected the string quota to be coerced, got %+v", result.Quota)
+	}
+}
+
+func TestQuota_UnmarshalLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		expected  interface{}
+		limit     int
+		ok        bool
+		unlimited bool
+	}{
+		{"int", `{"quota": 100}`, 100, 100, true, false},
+		{"integral float", `{"quota": 100.0}`, 100, 100, true, false},
+		{"exponent", `{"quota": 1e2}`, 100, 100, true, false},
+		{"fractional", `{"quota": 100.5}`, 100.5, 100, true, false},
+		{"unlimited", `{"quota": "unlimited"}`, "unlimited", 0, false, true},
+		{"null", `{"quota": null}`, nil, 0, false, false},
+		{"missing", `{}`, nil, 0, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quota Quota
+			if err := json.Unmarshal([]byte(tt.json), &quota); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if quota.Quota != tt.expected {
+				t.Errorf("Expected Quota %#v, got %#v", tt.expected, quota.Quota)
+			}
+			if limit, ok := quota.Limit(); limit != tt.limit || ok != tt.ok {
+				t.Errorf("Expected Limit (%v, %v), got (%v, %v)", tt.limit, tt.ok, limit, ok)
+			}
+			if quota.IsUnlimited() != tt.unlimited {
+				t.Errorf("Expected IsUnlimited %v", tt.unlimited)
+			}
+		})
+	}
+
+	// Quotas built by hand report the same limit as decoded ones
+	for _, quota := range []Quota{{Quota: 100}, {Quota: float64(100)}, {Quota: json.Number("100")}} {
+		if limit, ok := quota.Limit(); limit != 100 || !ok {
+			t.Errorf("Expected Limit (100, true) for %#v, got (%v, %v)", quota.Quota, limit, ok)
+		}
+	}
+}
+
+func TestQuota_PercentUsed(t *testing.T) {
+	tests := []struct {
+		name     string
+		quota    Quota
+		expected float64
+		ok       bool
+	}{
+		{"int limit", Quota{Used: 25, Remaining: 75, Quota: 100}, 25, true},
+		{"decoded limit", Quota{Used: 10, Remaining: 190, Quota: float64(200)}, 5, true},
+		{"unlimited", Quota{Used: 10, Quota: "unlimited"}, 0, false},
+		{"zero limit", Quota{Used: 0, Quota: 0}, 0, false},
+		{"missing limit", Quota{Used: 10}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			percent, ok := tt.quota.PercentUsed()
+			if ok != tt.ok || percent != tt.expected {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.expected, tt.ok, percent, ok)
+			}
+		})
+	}
+}
+
+func TestQuota_RemainingValidations(t *testing.T) {
+	tests := []struct {
+		name      string
+		quota     Quota
+		remaining int
+		bounded   bool
+	}{
+		{"bounded", Quota{Used: 25, Remaining: 75, Quota: 100}, 75, true},
+		{"exhausted", Quota{Used: 100, Remaining: 0, Quota: float64(100)}, 0, true},
+		{"overdrawn", Quota{Used: 105, Remaining: -5, Quota: 100}, 0, true},
+		{"unlimited", Quota{Used: 10, Remaining: 0, Quota: "unlimited"}, 0, false},
+		{"unlimited uppercase", Quota{Used: 10, Quota: "UNLIMITED"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remaining, bounded := tt.quota.RemainingValidations()
+			if remaining != tt.remaining || bounded != tt.bounded {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.remaining, tt.bounded, remaining, bounded)
+			}
+		})
+	}
+}
+
+func TestValidateChallengeResponse_Result(t *testing.T) {
+	t.Run("valid with quota", func(t *testing.T) {
+		response := ValidateChallengeResponse{Valid: boolPtr(true), Quota: &Quota{Used: 25, Remaining: 75, Quota: 100}}
+		valid, remaining, err := response.Result()
+		if !valid || remaining != 75 || err != nil {
+			t.Errorf("Expected (true, 75, nil), got (%v, %v, %v)", valid, remaining, err)
+		}
+	})
+
+	t.Run("invalid with error", func(t *testing.T) {
+		response := ValidateChallengeResponse{Valid: boolPtr(false), Quota: &Quota{Remaining: 10}, Error: stringPtr("challenge_expired")}
+		valid, remaining, err := response.Result()
+		if valid || remaining != 10 {
+			t.Errorf("Expected (false, 10), got (%v, %v)", valid, remaining)
+		}
+
+		var keyClaimErr *KeyClaimError
+		if !errors.As(err, &keyClaimErr) || keyClaimErr.Code != "challenge_expired" {
+			t.Fatalf("Expected KeyClaimError with code challenge_expired, got %v", err)
+		}
+		if !errors.Is(err, ErrChallengeExpired) {
+			t.Error("Expected the error to match ErrChallengeExpired")
+		}
+	})
+
+	t.Run("no quota", func(t *testing.T) {
+		response := ValidateChallengeResponse{Valid: boolPtr(true)}
+		valid, remaining, err := response.Result()
+		if !valid || remaining != -1 || err != nil {
+			t.Errorf("Expected (true, -1, nil), got (%v, %v, %v)", valid, remaining, err)
+		}
+	})
+}
+
+func TestValidateChallengeResponse_ParseSignature(t *testing.T) {
+	mac := bytes.Repeat([]byte{0xab}, sha256.Size)
+	raw := append([]byte{0, 0, 0, 0, 0x6a, 0xe6, 0x2c, 0x00}, mac...) // 1793469440
+	response := ValidateChallengeResponse{Valid: boolPtr(true), Signature: stringPtr(base64.StdEncoding.EncodeToString(raw))}
+
+	info, err := response.ParseSignature()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !info.Timestamp.Equal(time.Unix(1793469440, 0)) {
+		t.Errorf("Expected timestamp %v, got %v", time.Unix(1793469440, 0), info.Timestamp)
+	}
+	if !bytes.Equal(info.MAC, mac) {
+		t.Errorf("Expected MAC %x, got %x", mac, info.MAC)
+	}
+
+	for _, signature := range []*string{nil, stringPtr("not base64!"), stringPtr(base64.StdEncoding.EncodeToString(mac))} {
+		response := ValidateChallengeResponse{Signature: signature}
+		if _, err := response.ParseSignature(); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature for %v, got %v", signature, err)
+		}
+	}
+}
+
+func TestVerifyResponse_AdditionalSecrets(t *testing.T) {
+	oldClient, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "old-secret")
+	verifier, _ := NewClientWithConfig(Config{
+		APIKey:            "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret:            "new-secret",
+		AdditionalSecrets: []string{"old-secret"},
+	})
+
+	for _, method := range []ResponseMethod{ResponseMethodHMAC, ResponseMethodHash} {
+		response, _ := oldClient.GenerateResponse("test-challenge", method, nil)
+
+		ok, err := verifier.VerifyResponse("test-challenge", response, method, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !ok {
+			t.Errorf("Expected %s response from the secondary secret to verify", method)
+		}
+
+		ok, _ = verifier.VerifyResponse("test-challenge", "0000", method, nil)
+		if ok {
+			t.Errorf("Expected bogus %s response not to verify", method)
+		}
+	}
+}
+
+func TestValidate_ExpiredChallenge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/challenge/validate" {
+			t.Error("Expected validate not to be called for an expired challenge")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 0})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if !errors.Is(err, ErrChallengeExpired) {
+		t.Fatalf("Expected ErrChallengeExpired, got %v", err)
+	}
+}
+
+func TestGenerateResponseWithNonce(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	first, firstNonce, err := client.GenerateResponseWithNonce("test-challenge", ResponseMethodCustom, "custom-string")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, secondNonce, err := client.GenerateResponseWithNonce("test-challenge", ResponseMethodCustom, "custom-string")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(firstNonce) != 32 {
+		t.Errorf("Expected nonce length 32, got %d", len(firstNonce))
+	}
+	if firstNonce == secondNonce {
+		t.Error("Expected different nonces")
+	}
+	if first == second {
+		t.Error("Expected different nonces to yield different responses")
+	}
+
+	expected, _ := client.GenerateResponse("test-challenge:"+firstNonce, ResponseMethodCustom, "custom-string")
+	if first != expected {
+		t.Errorf("Expected response %s for the documented pre-image, got %s", expected, first)
+	}
+}
+
+func TestGenerateResponseWithSalt(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	client.baseURL = server.URL
+
+	response, salt, err := client.GenerateResponseWithSalt("test-challenge", ResponseMethodHMAC, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(salt) != 32 {
+		t.Errorf("Expected salt length 32, got %d", len(salt))
+	}
+	expected, _ := client.GenerateResponse("test-challenge:"+salt, ResponseMethodHMAC, nil)
+	if response != expected {
+		t.Errorf("Expected response %s for the documented pre-image, got %s", expected, response)
+	}
+
+	ctx := context.Background()
+	if _, err := client.ValidateChallengeWithOptions(ctx, ValidateChallengeOptions{Challenge: "test-challenge", Response: response, Salt: salt}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.ValidateChallengeWithOptions(ctx, ValidateChallengeOptions{Challenge: "test-challenge", Response: response}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(bodies))
+	}
+	if bodies[0]["salt"] != salt {
+		t.Errorf("Expected salt %s in the validate body, got %v", salt, bodies[0]["salt"])
+	}
+	if _, ok := bodies[1]["salt"]; ok {
+		t.Error("Expected no salt field when Salt is empty")
+	}
+}
+
+func TestGenerateResponseAt(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	ts := time.Unix(1793491200, 0)
+
+	for _, method := range []ResponseMethod{ResponseMethodHMAC, ResponseMethodHash} {
+		first, timestamp, err := client.GenerateResponseAt("test-challenge", method, ts)
+		if err != nil {
+			t.Fatalf("Expected no error for %s, got %v", method, err)
+		}
+		if timestamp != "1793491200" {
+			t.Errorf("Expected timestamp 1793491200, got %s", timestamp)
+		}
+
+		second, _, _ := client.GenerateResponseAt("test-challenge", method, ts.Add(time.Second))
+		if first == second {
+			t.Errorf("Expected different timestamps to yield different %s responses", method)
+		}
+
+		expected, _ := client.GenerateResponse("test-challenge:1793491200", method, nil)
+		if first != expected {
+			t.Errorf("Expected %s response %s for the documented pre-image, got %s", method, expected, first)
+		}
+	}
+
+	if _, _, err := client.GenerateResponseAt("test-challenge", ResponseMethodEcho, ts); err == nil {
+		t.Error("Expected error for the echo method")
+	}
+}
+
+func TestCreateChallengeWithMeta_RateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-RateLimit-Limit", "100")
+		w.Header().Set("X-RateLimit-Remaining", "42")
+		w.Header().Set("X-RateLimit-Reset", "1793491200")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, meta, err := client.CreateChallengeWithMeta(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if meta.RateLimit == nil {
+		t.Fatal("Expected rate limit to be parsed")
+	}
+	if meta.RateLimit.Limit != 100 || meta.RateLimit.Remaining != 42 {
+		t.Errorf("Expected limit 100 and remaining 42, got %+v", meta.RateLimit)
+	}
+	if !meta.RateLimit.Reset.Equal(time.Unix(1793491200, 0)) {
+		t.Errorf("Expected reset at %v, got %v", time.Unix(1793491200, 0), meta.RateLimit.Reset)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	if rateLimit := parseRateLimit(http.Header{}, now); rateLimit != nil {
+		t.Errorf("Expected nil without headers, got %+v", rateLimit)
+	}
+
+	h := http.Header{}
+	h.Set("X-RateLimit-Remaining", "5")
+	h.Set("X-RateLimit-Reset", "60")
+	rateLimit := parseRateLimit(h, now)
+	if rateLimit == nil || rateLimit.Remaining != 5 {
+		t.Fatalf("Expected remaining 5, got %+v", rateLimit)
+	}
+	if !rateLimit.Reset.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected relative reset %v, got %v", now.Add(time.Minute), rateLimit.Reset)
+	}
+	if rateLimit.Limit != -1 {
+		t.Errorf("Expected limit -1 without its header, got %d", rateLimit.Limit)
+	}
+
+	h = http.Header{}
+	h.Set("X-RateLimit-Limit", "100")
+	rateLimit = parseRateLimit(h, now)
+	if rateLimit == nil || rateLimit.Limit != 100 {
+		t.Fatalf("Expected limit 100, got %+v", rateLimit)
+	}
+	if rateLimit.Remaining != -1 {
+		t.Errorf("Expected remaining -1 without its header, got %d", rateLimit.Remaining)
+	}
+	if !rateLimit.Reset.IsZero() {
+		t.Errorf("Expected a zero reset without its header, got %v", rateLimit.Reset)
+	}
+}
+
+func TestGenerateResponseFromReader(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	data := strings.Repeat("0123456789abcdef", 256*1024) // 4 MiB
+	response, err := client.GenerateResponseFromReader("test-challenge", strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expected, _ := client.GenerateResponse("test-challenge", ResponseMethodCustom, data)
+	if response != expected {
+		t.Errorf("Expected streamed response %s to match in-memory %s", response, expected)
+	}
+}
+
+func TestValidate_SendCustomData(t *testing.T) {
+	var validateBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			validateBody = nil
+			json.NewDecoder(r.Body).Decode(&validateBody)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	customData := map[string]interface{}{"userId": "user-1", "action": "login"}
+	expected, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	expectedResponse, _ := expected.GenerateResponse("test-challenge", ResponseMethodCustom, customData)
+
+	newClient := func(send bool) *KeyClaimClient {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+			Secret:         "test-secret",
+			SendCustomData: send,
+		})
+		client.baseURL = server.URL
+		return client
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		if _, err := newClient(true).Validate(ResponseMethodCustom, 30, customData); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if validateBody["response"] != expectedResponse {
+			t.Errorf("Expected response %s, got %v", expectedResponse, validateBody["response"])
+		}
+		sent, _ := validateBody["custom_data"].(map[string]interface{})
+		if sent["userId"] != "user-1" || sent["action"] != "login" {
+			t.Errorf("Expected the custom data in the validate body, got %v", validateBody)
+		}
+	})
+
+	t.Run("other methods", func(t *testing.T) {
+		if _, err := newClient(true).Validate(ResponseMethodHMAC, 30, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, ok := validateBody["custom_data"]; ok {
+			t.Errorf("Expected no custom data for the hmac method, got %v", validateBody)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if _, err := newClient(false).Validate(ResponseMethodCustom, 30, customData); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if _, ok := validateBody["custom_data"]; ok || validateBody["response"] != expectedResponse {
+			t.Errorf("Expected only the response by default, got %v", validateBody)
+		}
+	})
+}
+
+func TestValidate_AutoRefreshExpired(t *testing.T) {
+	var ttls []int
+	validations := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			var body CreateChallengeOptions
+			json.NewDecoder(r.Body).Decode(&body)
+			ttls = append(ttls, body.TTL)
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			validations++
+			if validations == 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"challenge_expired"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:             "kc_test123456789012345678901234567890123456789012345678901234567890",
+		AutoRefreshExpired: true,
+	})
+	client.baseURL = server.URL
+
+	result, err := client.Validate(ResponseMethodHMAC, 45, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid after refresh")
+	}
+	if len(ttls) != 2 || ttls[0] != 45 || ttls[1] != 45 {
+		t.Errorf("Expected two creates with ttl 45, got %v", ttls)
+	}
+}
+
+func TestValidate_AutoRefreshExpiredOnlyOnce(t *testing.T) {
+	validations := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			validations++
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"challenge_expired"}`))
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:             "kc_test123456789012345678901234567890123456789012345678901234567890",
+		AutoRefreshExpired: true,
+	})
+	client.baseURL = server.URL
+
+	_, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if !errors.Is(err, ErrChallengeExpired) {
+		t.Fatalf("Expected ErrChallengeExpired, got %v", err)
+	}
+	if validations != 2 {
+		t.Errorf("Expected exactly 2 validations, got %d", validations)
+	}
+}
+
+func TestKeyDeriver(t *testing.T) {
+	// RFC 5869 test case 1
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm, _ := hex.DecodeString("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+
+	var derivedFrom string
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret: string(ikm),
+		KeyDeriver: func(secret string) []byte {
+			derivedFrom = secret
+			return hkdfSHA256([]byte(secret), salt, info, 42)
+		},
+	})
+
+	if derivedFrom != string(ikm) {
+		t.Fatal("Expected KeyDeriver to be called with the secret")
+	}
+	if !bytes.Equal(client.key, okm) {
+		t.Fatalf("Expected derived key %x, got %x", okm, client.key)
+	}
+
+	response, _ := client.GenerateResponse("test-challenge", ResponseMethodHMAC, nil)
+	h := hmac.New(sha256.New, okm)
+	h.Write([]byte("test-challenge"))
+	if expected := hex.EncodeToString(h.Sum(nil)); response != expected {
+		t.Errorf("Expected HMAC keyed with the derived key %s, got %s", expected, response)
+	}
+}
+
+func TestSecretBytes(t *testing.T) {
+	secret := []byte{0x00, 0xff, 0x10, 0x80, 0xc3, 0x28} // Not valid UTF-8
+	client, err := NewClientWithConfig(Config{
+		APIKey:      "kc_test123456789012345678901234567890123456789012345678901234567890",
+		SecretBytes: secret,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	secret[0] = 0x01
+
+	response, _ := client.GenerateResponse("test-challenge", ResponseMethodHMAC, nil)
+	if expected := "93732ad126042a53e8a26aa41b479c7579790cab40d6822d77deba871c10f4b9"; response != expected {
+		t.Errorf("Expected HMAC %s, got %s", expected, response)
+	}
+
+	_, err = NewClientWithConfig(Config{
+		APIKey:      "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret:      "test-secret",
+		SecretBytes: secret,
+	})
+	if err == nil {
+		t.Error("Expected error when both Secret and SecretBytes are set")
+	}
+}
+
+// hkdfSHA256 implements HKDF (RFC 5869) with SHA-256
+func hkdfSHA256(secret, salt, info []byte, length int) []byte {
+	extract := hmac.New(sha256.New, salt)
+	extract.Write(secret)
+	prk := extract.Sum(nil)
+
+	var okm, block []byte
+	for counter := byte(1); len(okm) < length; counter++ {
+		expand := hmac.New(sha256.New, prk)
+		expand.Write(block)
+		expand.Write(info)
+		expand.Write([]byte{counter})
+		block = expand.Sum(nil)
+		okm = append(okm, block...)
+	}
+	return okm[:length]
+}
+
+func TestGenerateResponse_EchoTransform(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform func(string) string
+		expected  string
+	}{
+		{"identity", nil, "test-challenge"},
+		{"base64", func(challenge string) string {
+			return base64.StdEncoding.EncodeToString([]byte(challenge))
+		}, "dGVzdC1jaGFsbGVuZ2U="},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := NewClientWithConfig(Config{
+				APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+				EchoTransform: tt.transform,
+			})
+
+			response, err := client.GenerateResponse("test-challenge", ResponseMethodEcho, nil)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, response)
+			}
+		})
+	}
+}
+
+func TestValidateChallenge_FallbackBaseURLs(t *testing.T) {
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primary.Close() // Unreachable
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer secondary.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:           "kc_test123456789012345678901234567890123456789012345678901234567890",
+		FallbackBaseURLs: []string{secondary.URL},
+		RequireHTTPS:     boolPtr(false),
+	})
+	client.baseURL = primary.URL
+
+	result, meta, err := client.ValidateChallengeWithMeta(context.Background(), "test-challenge", "test-response", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+	if meta.Endpoint != secondary.URL {
+		t.Errorf("Expected secondary %s to answer, got %s", secondary.URL, meta.Endpoint)
+	}
+}
+
+func TestValidateChallenge_FallbackOnServerError(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer primary.Close()
+
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("Invalid response")})
+	}))
+	defer secondary.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:           "kc_test123456789012345678901234567890123456789012345678901234567890",
+		FallbackBaseURLs: []string{secondary.URL},
+		RequireHTTPS:     boolPtr(false),
+	})
+	client.baseURL = primary.URL
+
+	result, meta, err := client.ValidateChallengeWithMeta(context.Background(), "test-challenge", "test-response", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.IsValid() {
+		t.Error("Expected the secondary's definitive invalid result")
+	}
+	if meta.Endpoint != secondary.URL {
+		t.Errorf("Expected secondary %s to answer, got %s", secondary.URL, meta.Endpoint)
+	}
+}
+
+func TestGenerateResponse_ValidateChallengeEncoding(t *testing.T) {
+	invalid := "test-\xff-challenge"
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	if _, err := client.GenerateResponse(invalid, ResponseMethodHMAC, nil); err != nil {
+		t.Fatalf("Expected no validation by default, got %v", err)
+	}
+
+	client, _ = NewClientWithConfig(Config{
+		APIKey:                    "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ValidateChallengeEncoding: true,
+	})
+
+	for _, challenge := range []string{invalid, "test-\x00-challenge", "test\nchallenge"} {
+		if _, err := client.GenerateResponse(challenge, ResponseMethodHMAC, nil); !errors.Is(err, ErrInvalidChallengeEncoding) {
+			t.Errorf("Expected ErrInvalidChallengeEncoding for %q, got %v", challenge, err)
+		}
+	}
+
+	if _, err := client.GenerateResponse("test-challenge-ü", ResponseMethodHMAC, nil); err != nil {
+		t.Errorf("Expected printable UTF-8 challenge to pass, got %v", err)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var requestIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get("X-Request-ID"))
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/challenge/validate" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_api_key"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	if _, err := client.CreateChallengeContext(ctx, 30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	_, err := client.ValidateChallengeContext(ctx, "test-challenge", "test-response", nil)
+
+	var keyClaimErr *KeyClaimError
+	if !errors.As(err, &keyClaimErr) || keyClaimErr.RequestID != "req-123" {
+		t.Errorf("Expected error carrying request ID req-123, got %v", err)
+	}
+
+	client.CreateChallenge(30)
+
+	if len(requestIDs) != 3 || requestIDs[0] != "req-123" || requestIDs[1] != "req-123" || requestIDs[2] != "" {
+		t.Errorf("Expected request IDs [req-123 req-123 \"\"], got %q", requestIDs)
+	}
+}
+
+func TestRequestID_CustomExtractor(t *testing.T) {
+	type traceKey struct{}
+
+	var requestID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get("X-Request-ID")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		RequestIDFromContext: func(ctx context.Context) string {
+			id, _ := ctx.Value(traceKey{}).(string)
+			return id
+		},
+	})
+	client.baseURL = server.URL
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-456")
+	if _, err := client.CreateChallengeContext(ctx, 30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requestID != "trace-456" {
+		t.Errorf("Expected X-Request-ID trace-456, got %q", requestID)
+	}
+}
+
+func TestMaxConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxConcurrency: 2,
+	})
+	client.baseURL = server.URL
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.CreateChallenge(30); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxInFlight > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", maxInFlight)
+	}
+}
+
+func TestMaxConcurrency_FailFastAndCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxConcurrency: 1,
+	})
+	client.baseURL = server.URL
+
+	done := make(chan struct{})
+	go func() {
+		client.CreateChallenge(30)
+		close(done)
+	}()
+	for len(client.slots) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.CreateChallengeContext(ctx, 30); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded while waiting for a slot, got %v", err)
+	}
+
+	failFast, _ := NewClientWithConfig(Config{
+		APIKey:                "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxConcurrency:        1,
+		FailFastOnConcurrency: true,
+	})
+	failFast.baseURL = server.URL
+	failFast.slots <- struct{}{} // Occupy the only slot
+	if _, err := failFast.CreateChallenge(30); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Errorf("Expected ErrConcurrencyLimit, got %v", err)
+	}
+
+	close(release)
+	<-done
+}
+
+func TestValidate_ForwardsChallengeID(t *testing.T) {
+	var forwarded ValidateChallengeOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			w.Write([]byte(`{"challenge":"test-challenge-123","expires_in":30,"id":"ch_123"}`))
+		case "/api/challenge/validate":
+			json.NewDecoder(r.Body).Decode(&forwarded)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	challenge, err := client.CreateChallenge(30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.ChallengeID != "ch_123" {
+		t.Errorf("Expected challenge ID ch_123, got %q", challenge.ChallengeID)
+	}
+
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if forwarded.ChallengeID != "ch_123" {
+		t.Errorf("Expected challenge ID ch_123 to be forwarded, got %q", forwarded.ChallengeID)
+	}
+}
+
+func TestHandleErrorResponseFromBody_CodeAndMessage(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantMessage string
+	}{
+		{"error only", `{"error":"invalid_api_key"}`, "invalid_api_key", "invalid_api_key"},
+		{"message only", `{"message":"The API key is invalid"}`, "", "The API key is invalid"},
+		{"both", `{"message":"The API key is invalid","error":"invalid_api_key"}`, "invalid_api_key", "The API key is invalid"},
+		{"neither", `{}`, "", "Request failed"},
+		{"nested object", `{"error":{"code":"invalid_api_key","message":"The API key is invalid"}}`, "invalid_api_key", "The API key is invalid"},
+		{"numeric code", `{"error":4010,"message":"The API key is invalid"}`, "4010", "The API key is invalid"},
+		{"top-level array", `[{"error":"invalid_api_key"}]`, "", "Request failed"},
+		{"non-string message", `{"error":"invalid_api_key","message":["a","b"]}`, "invalid_api_key", "invalid_api_key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := client.handleErrorResponseFromBody([]byte(tt.body), http.StatusUnauthorized, "Request failed")
+
+			var keyClaimErr *KeyClaimError
+			if !errors.As(err, &keyClaimErr) {
+				t.Fatalf("Expected *KeyClaimError, got %T", err)
+			}
+			if keyClaimErr.Code != tt.wantCode {
+				t.Errorf("Expected code %q, got %q", tt.wantCode, keyClaimErr.Code)
+			}
+			if keyClaimErr.Message != tt.wantMessage {
+				t.Errorf("Expected message %q, got %q", tt.wantMessage, keyClaimErr.Message)
+			}
+		})
+	}
+}
+
+func FuzzErrorParsing(f *testing.F) {
+	f.Add([]byte(`{"error":"invalid_api_key","message":"The API key is invalid"}`))
+	f.Add([]byte(`{"error":{"code":4010,"message":"nested"}}`))
+	f.Add([]byte(`{"error":"quota_exceeded","quota":{"used":"x","reset_at":1}}`))
+	f.Add([]byte(`[1,2,3]`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`not json`))
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		err := client.handleErrorResponseFromBody(body, http.StatusBadRequest, "Request failed")
+
+		var keyClaimErr *KeyClaimError
+		if !errors.As(err, &keyClaimErr) {
+			t.Fatalf("Expected *KeyClaimError, got %T", err)
+		}
+		if keyClaimErr.Message == "" {
+			t.Error("Expected a non-empty message")
+		}
+	})
+}
+
+func TestCreateChallenge_ZeroTTL(t *testing.T) {
+	var requestedTTL int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TTL int `json:"ttl"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requestedTTL = body.TTL
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: body.TTL})
+	}))
+	defer server.Close()
+
+	t.Run("lenient", func(t *testing.T) {
+		client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+		client.baseURL = server.URL
+
+		if _, err := client.CreateChallenge(0); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if requestedTTL != defaultTTL {
+			t.Errorf("Expected the default TTL %d, got %d", defaultTTL, requestedTTL)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		requestedTTL = 0
+		client, _ := NewClientWithConfig(Config{
+			APIKey:    "kc_test123456789012345678901234567890123456789012345678901234567890",
+			StrictTTL: true,
+		})
+		client.baseURL = server.URL
+
+		if _, err := client.CreateChallenge(0); !errors.Is(err, ErrZeroTTL) {
+			t.Fatalf("Expected ErrZeroTTL, got %v", err)
+		}
+		if requestedTTL != 0 {
+			t.Error("Expected no request to be sent")
+		}
+
+		if _, err := client.CreateChallenge(60); err != nil {
+			t.Fatalf("Expected no error for an explicit TTL, got %v", err)
+		}
+		if requestedTTL != 60 {
+			t.Errorf("Expected TTL 60, got %d", requestedTTL)
+		}
+	})
+}
+
+func TestCreateChallenge_TTLUnit(t *testing.T) {
+	var requestedTTL int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			TTL int64 `json:"ttl"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		requestedTTL = body.TTL
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		unit     TTLUnit
+		seconds  int           // Passed to CreateChallenge when duration is zero
+		duration time.Duration // Passed to CreateChallengeDuration
+		want     int64
+	}{
+		{"seconds", TTLUnitSeconds, 45, 0, 45},
+		{"milliseconds", TTLUnitMilliseconds, 45, 0, 45000},
+		{"default milliseconds", TTLUnitMilliseconds, 0, 0, defaultTTL * 1000},
+		{"duration seconds", TTLUnitSeconds, 0, 2 * time.Minute, 120},
+		{"duration rounds up", TTLUnitSeconds, 0, 1500 * time.Millisecond, 2},
+		{"duration milliseconds", TTLUnitMilliseconds, 0, 1500 * time.Millisecond, 1500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, _ := NewClientWithConfig(Config{
+				APIKey:  "kc_test123456789012345678901234567890123456789012345678901234567890",
+				TTLUnit: tt.unit,
+			})
+			client.baseURL = server.URL
+
+			var err error
+			if tt.duration != 0 {
+				_, err = client.CreateChallengeDuration(tt.duration)
+			} else {
+				_, err = client.CreateChallenge(tt.seconds)
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if requestedTTL != tt.want {
+				t.Errorf("Expected TTL %d, got %d", tt.want, requestedTTL)
+			}
+		})
+	}
+}
+
+func TestBodyInterceptor(t *testing.T) {
+	var received map[string]interface{}
+	var contentLength int64
+	var bodyLength int
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		contentLength = r.ContentLength
+		body, _ := io.ReadAll(r.Body)
+		bodyLength = len(body)
+		json.Unmarshal(body, &received)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	var interceptedPath string
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BodyInterceptor: func(path string, body []byte) ([]byte, error) {
+			interceptedPath = path
+			var fields map[string]interface{}
+			if err := json.Unmarshal(body, &fields); err != nil {
+				return nil, err
+			}
+			fields["signature"] = "sig-123"
+			return json.Marshal(fields)
+		},
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if interceptedPath != "/api/challenge/create" {
+		t.Errorf("Expected the create path, got %q", interceptedPath)
+	}
+	if received["signature"] != "sig-123" || received["ttl"] != float64(30) {
+		t.Errorf("Expected the injected field alongside ttl, got %v", received)
+	}
+	if contentLength != int64(bodyLength) {
+		t.Errorf("Expected Content-Length %d to match the modified body, got %d", bodyLength, contentLength)
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		requests = 0
+		abort := errors.New("refused")
+		client.bodyInterceptor = func(path string, body []byte) ([]byte, error) { return nil, abort }
+
+		if _, err := client.CreateChallenge(30); !errors.Is(err, abort) {
+			t.Fatalf("Expected the interceptor's error, got %v", err)
+		}
+		if requests != 0 {
+			t.Error("Expected no request to be sent")
+		}
+	})
+
+	t.Run("retained body", func(t *testing.T) {
+		var retained [][]byte
+		client.bodyInterceptor = func(path string, body []byte) ([]byte, error) {
+			retained = append(retained, body)
+			return body, nil
+		}
+
+		client.CreateChallenge(30)
+		client.CreateChallenge(45)
+		if string(retained[0]) != `{"ttl":30}` {
+			t.Errorf("Expected the retained body to survive later requests, got %s", retained[0])
+		}
+	})
+}
+
+func TestWithHeaders(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = append(headers, r.Header.Clone())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	perCall := map[string]string{"X-Trace-Tag": "checkout", "Authorization": "Bearer other"}
+	ctx := WithHeaders(context.Background(), perCall)
+	ctx = WithHeaders(ctx, map[string]string{"X-Tenant": "acme"})
+	perCall["X-Trace-Tag"] = "modified"
+
+	if _, err := client.CreateChallengeContext(ctx, 30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	client.CreateChallenge(30)
+
+	if len(headers) != 2 {
+		t.Fatalf("Expected 2 requests, got %d", len(headers))
+	}
+	if got := headers[0].Get("X-Trace-Tag"); got != "checkout" {
+		t.Errorf("Expected X-Trace-Tag checkout, got %q", got)
+	}
+	if got := headers[0].Get("X-Tenant"); got != "acme" {
+		t.Errorf("Expected X-Tenant acme, got %q", got)
+	}
+	if got := headers[0].Get("Authorization"); got != "Bearer "+client.apiKey {
+		t.Errorf("Expected the client's Authorization header, got %q", got)
+	}
+	if headers[1].Get("X-Trace-Tag") != "" || headers[1].Get("X-Tenant") != "" {
+		t.Errorf("Expected per-call headers not to leak into other calls, got %v", headers[1])
+	}
+}
+
+func TestFieldMap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			if body["ttl_seconds"] != float64(45) {
+				t.Errorf("Expected ttl_seconds 45, got %v", body)
+			}
+			w.Write([]byte(`{"token":"remapped-challenge","expires_in":45}`))
+		case "/api/challenge/validate":
+			if body["token"] != "remapped-challenge" {
+				t.Errorf("Expected token remapped-challenge, got %v", body)
+			}
+			if _, ok := body["challenge"]; ok {
+				t.Errorf("Expected no challenge field, got %v", body)
+			}
+			w.Write([]byte(`{"valid":true}`))
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:   "kc_test123456789012345678901234567890123456789012345678901234567890",
+		FieldMap: map[string]string{"challenge": "token", "ttl": "ttl_seconds"},
+	})
+	client.baseURL = server.URL
+
+	challenge, err := client.CreateChallenge(45)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.Challenge != "remapped-challenge" {
+		t.Errorf("Expected challenge 'remapped-challenge', got %q", challenge.Challenge)
+	}
+
+	result, err := client.ValidateChallenge(challenge.Challenge, "test-response", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to succeed")
+	}
+}
+
+func TestValidate_SuccessPredicate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{
+				Valid: boolPtr(true),
+				Quota: &Quota{Used: 100, Remaining: 0, Quota: 100},
+			})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ValidationSuccessPredicate: func(result *ValidateChallengeResponse) bool {
+			return result.IsValid() && result.Quota != nil && result.Quota.Remaining > 0
+		},
+	})
+	client.baseURL = server.URL
+
+	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if !errors.Is(err, ErrValidationRejected) {
+		t.Fatalf("Expected ErrValidationRejected, got %v", err)
+	}
+	if result == nil || !result.IsValid() {
+		t.Error("Expected the API's result to be returned alongside the error")
+	}
+	if client.Succeeded(result) {
+		t.Error("Expected Succeeded to apply the predicate")
+	}
+
+	method, result, err := client.ValidateAuto(30, nil)
+	if !errors.Is(err, ErrValidationRejected) {
+		t.Fatalf("Expected ValidateAuto to return ErrValidationRejected, got %v", err)
+	}
+	if method != ResponseMethodHMAC || result == nil {
+		t.Errorf("Expected the rejected method and its result, got %q, %v", method, result)
+	}
+
+	lenient, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	lenient.baseURL = server.URL
+	if _, err := lenient.Validate(ResponseMethodHMAC, 30, nil); err != nil {
+		t.Fatalf("Expected no error without a predicate, got %v", err)
+	}
+}
+
+func TestValidateAuto(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	expected, _ := client.GenerateResponse("test-challenge-123", ResponseMethodHash, nil)
+
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			attempts++
+			var body ValidateChallengeOptions
+			json.NewDecoder(r.Body).Decode(&body)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(body.Response == expected)})
+		}
+	}))
+	defer server.Close()
+	client.baseURL = server.URL
+
+	method, result, err := client.ValidateAuto(30, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if method != ResponseMethodHash {
+		t.Errorf("Expected method hash, got %q", method)
+	}
+	if !result.IsValid() {
+		t.Error("Expected a valid result")
+	}
+	if attempts != 2 {
+		t.Errorf("Expected 2 validation attempts (hmac, hash), got %d", attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := client.ValidateAutoContext(ctx, 30, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestValidateContext_CanceledAfterCreate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30, ChallengeID: "ch_123"})
+		case "/api/challenge/validate":
+			io.Copy(io.Discard, r.Body)
+			cancel()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.ValidateContext(ctx, ResponseMethodHMAC, 30, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	var canceledErr *FlowCanceledError
+	if !errors.As(err, &canceledErr) {
+		t.Fatalf("Expected *FlowCanceledError, got %T", err)
+	}
+	if canceledErr.Challenge == nil || canceledErr.Challenge.ChallengeID != "ch_123" {
+		t.Errorf("Expected the created challenge on the error, got %+v", canceledErr.Challenge)
+	}
+}
+
+func TestValidateWithBudget(t *testing.T) {
+	const budget = 100 * time.Millisecond
+
+	// sleep stalls a handler without outliving the client's request
+	sleep := func(r *http.Request, d time.Duration) {
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(d):
+		case <-r.Context().Done():
+		}
+	}
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		handler    http.HandlerFunc
+	}{
+		{
+			name: "slow create",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				sleep(r, time.Second)
+			},
+		},
+		{
+			name: "slow validate",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/api/challenge/validate" {
+					sleep(r, time.Second)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+			},
+		},
+		{
+			name:       "retries",
+			maxRetries: 10,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				sleep(r, 30*time.Millisecond)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			client, _ := NewClientWithConfig(Config{
+				APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+				MaxRetries:   tt.maxRetries,
+				RetryBackoff: 20 * time.Millisecond,
+			})
+			client.baseURL = server.URL
+
+			start := time.Now()
+			_, err := client.ValidateWithBudget(context.Background(), ResponseMethodHMAC, 30, nil, budget)
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed > budget+200*time.Millisecond {
+				t.Errorf("Expected the budget of %s to be respected, took %s", budget, elapsed)
+			}
+		})
+	}
+}
+
+func TestValidateWithBudget_WithinBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	result, err := client.ValidateWithBudget(context.Background(), ResponseMethodHMAC, 30, nil, time.Second)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+}
+
+func TestValidateFromChallengeJSON(t *testing.T) {
+	var validateReq ValidateChallengeOptions
+	validateCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30, ChallengeID: "ch_123"})
+		case "/api/challenge/validate":
+			validateCalls++
+			json.NewDecoder(r.Body).Decode(&validateReq)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	// Producer side: create the challenge and serialize it
+	challenge, err := client.CreateChallenge(30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.ExpiresAt == nil {
+		t.Fatal("Expected CreateChallenge to fill in ExpiresAt")
+	}
+	data, _ := json.Marshal(challenge)
+
+	// Consumer side
+	result, err := client.ValidateFromChallengeJSON(data, ResponseMethodHMAC, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+	expected, _ := client.GenerateResponse("test-challenge-123", ResponseMethodHMAC, nil)
+	if validateReq.Challenge != "test-challenge-123" || validateReq.Response != expected || validateReq.ChallengeID != "ch_123" {
+		t.Errorf("Unexpected validate request %+v", validateReq)
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		validateCalls = 0
+		expired := *challenge
+		past := time.Now().Add(-time.Second)
+		expired.ExpiresAt = &past
+		data, _ := json.Marshal(expired)
+
+		if _, err := client.ValidateFromChallengeJSON(data, ResponseMethodHMAC, nil); !errors.Is(err, ErrChallengeExpired) {
+			t.Fatalf("Expected ErrChallengeExpired, got %v", err)
+		}
+		if validateCalls != 0 {
+			t.Error("Expected validate not to be called for an expired challenge")
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		for _, data := range []string{`not json`, `{}`} {
+			if _, err := client.ValidateFromChallengeJSON([]byte(data), ResponseMethodHMAC, nil); err == nil {
+				t.Errorf("Expected error for %s", data)
+			}
+		}
+	})
+}
+
+func TestCreateChallenge_ClockSkew(t *testing.T) {
+	var offset time.Duration
+	validated := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverNow := time.Now().Add(offset)
+		w.Header().Set("Date", serverNow.UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			expiresAt := serverNow.Add(30 * time.Second)
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30, ExpiresAt: &expiresAt})
+		case "/api/challenge/validate":
+			validated = true
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	for _, offset = range []time.Duration{time.Hour, -time.Hour} {
+		challenge, err := client.CreateChallenge(30)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if skew := challenge.ClockSkew - offset; skew < -2*time.Second || skew > 2*time.Second {
+			t.Errorf("Expected a clock skew of about %s, got %s", offset, challenge.ClockSkew)
+		}
+		if remaining := time.Until(*challenge.ExpiresAt); remaining < 27*time.Second || remaining > 32*time.Second {
+			t.Errorf("Expected ExpiresAt about 30s from now by the local clock, got %s", remaining)
+		}
+	}
+
+	// A server an hour behind would otherwise make the challenge look long expired
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); err != nil || !validated {
+		t.Errorf("Expected the skew-corrected challenge to validate, got %v", err)
+	}
+
+	offset = 0
+	challenge, _ := client.CreateChallenge(30)
+	if challenge.ClockSkew != 0 {
+		t.Errorf("Expected no skew for a synchronized server, got %s", challenge.ClockSkew)
+	}
+}
+
+func TestValidate_MaxChallengeAge(t *testing.T) {
+	validateCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			validateCalls++
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	var client *KeyClaimClient
+	delay := 50 * time.Millisecond
+	client, _ = NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxChallengeAge: 20 * time.Millisecond,
+		// Delay the flow between receiving the challenge and validating it
+		ChallengeSource: func(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+			challenge, err := client.CreateChallengeContext(ctx, ttl)
+			time.Sleep(delay)
+			return challenge, err
+		},
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); !errors.Is(err, ErrStaleChallenge) {
+		t.Fatalf("Expected ErrStaleChallenge, got %v", err)
+	}
+	if validateCalls != 0 {
+		t.Error("Expected validate not to be called for a stale challenge")
+	}
+
+	delay = 0
+	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if err != nil {
+		t.Fatalf("Expected no error for a fresh challenge, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+
+	stub, _ := NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxChallengeAge: time.Minute,
+		ChallengeSource: func(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+			return &CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: ttl}, nil
+		},
+	})
+	stub.baseURL = server.URL
+
+	validateCalls = 0
+	if _, err := stub.Validate(ResponseMethodHMAC, 30, nil); !errors.Is(err, ErrStaleChallenge) {
+		t.Fatalf("Expected ErrStaleChallenge for an unknown receipt time, got %v", err)
+	}
+	if validateCalls != 0 {
+		t.Error("Expected validate not to be called for a challenge of unknown age")
+	}
+}
+
+func TestCreateChallengeResponse_AgeExceeds(t *testing.T) {
+	receivedAt := time.Now().Add(-time.Minute)
+	challenge := &CreateChallengeResponse{ReceivedAt: &receivedAt}
+	if !challenge.AgeExceeds(30 * time.Second) {
+		t.Error("Expected a minute-old challenge to exceed 30s")
+	}
+	if challenge.AgeExceeds(2 * time.Minute) {
+		t.Error("Expected a minute-old challenge not to exceed 2m")
+	}
+	if (&CreateChallengeResponse{}).AgeExceeds(0) {
+		t.Error("Expected an unknown receipt time not to exceed any age")
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/challenge/validate" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"limit_reached"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	var classified []int
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ErrorClassifier: func(statusCode int, body []byte) error {
+			classified = append(classified, statusCode)
+			if statusCode == http.StatusConflict {
+				return ErrQuotaExceeded
+			}
+			return nil
+		},
+	})
+	client.baseURL = server.URL
+
+	challenge, err := client.CreateChallenge(30)
+	if err != nil {
+		t.Fatalf("Expected responses the classifier passes on to decode normally, got %v", err)
+	}
+	if challenge.Challenge != "test-challenge-123" {
+		t.Errorf("Expected challenge 'test-challenge-123', got %s", challenge.Challenge)
+	}
+
+	if _, err := client.ValidateChallenge("test-challenge", "test-response", nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	if len(classified) != 2 || classified[0] != http.StatusOK || classified[1] != http.StatusConflict {
+		t.Errorf("Expected both responses to be classified, got %v", classified)
+	}
+}
+
+func TestStatusHandlers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_api_key","message":"API key revoked"}`))
+		case "/api/challenge/validate":
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"error":"quota_exceeded"}`))
+		}
+	}))
+	defer server.Close()
+
+	errReauthenticate := errors.New("re-authentication required")
+	var alerted []string
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		StatusHandlers: map[int]func(*http.Response) error{
+			http.StatusUnauthorized: func(resp *http.Response) error {
+				body, _ := io.ReadAll(resp.Body)
+				return fmt.Errorf("%w: %s", errReauthenticate, body)
+			},
+			http.StatusPaymentRequired: func(resp *http.Response) error {
+				body, _ := io.ReadAll(resp.Body)
+				alerted = append(alerted, string(body))
+				return nil
+			},
+		},
+	})
+	client.baseURL = server.URL
+
+	_, err := client.CreateChallenge(30)
+	if !errors.Is(err, errReauthenticate) || !strings.Contains(err.Error(), "API key revoked") {
+		t.Fatalf("Expected the 401 handler's error, got %v", err)
+	}
+
+	// A nil return continues with the default mapping, with the body intact
+	if _, err := client.ValidateChallenge("test-challenge", "test-response", nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if len(alerted) != 1 || !strings.Contains(alerted[0], "quota_exceeded") {
+		t.Errorf("Expected the 402 handler to see the body, got %v", alerted)
+	}
+}
+
+func TestValidateChallengeWithOptions_Metadata(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, err := client.ValidateChallengeWithOptions(context.Background(), ValidateChallengeOptions{
+		Challenge: "test-challenge",
+		Response:  "test-response",
+		Metadata: map[string]interface{}{
+			"device":    "ios",
+			"challenge": "overwritten",
+			"response":  "overwritten",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if body["challenge"] != "test-challenge" || body["response"] != "test-response" {
+		t.Errorf("Expected the standard fields to be kept, got %v", body)
+	}
+	if body["device"] != "ios" {
+		t.Errorf("Expected metadata field device=ios, got %v", body)
+	}
+	if _, ok := body["Metadata"]; ok {
+		t.Errorf("Expected metadata to be merged rather than nested, got %v", body)
+	}
+}
+
+func TestValidateChallengeWithOptions_MetadataWithFieldMap(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:   "kc_test123456789012345678901234567890123456789012345678901234567890",
+		FieldMap: map[string]string{"challenge": "token"},
+	})
+	client.baseURL = server.URL
+
+	// Map iteration order varies, so a collision would show up across runs
+	for i := 0; i < 20; i++ {
+		_, err := client.ValidateChallengeWithOptions(context.Background(), ValidateChallengeOptions{
+			Challenge: "real",
+			Response:  "test-response",
+			Metadata:  map[string]interface{}{"token": "evil", "device": "ios"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if body["token"] != "real" {
+			t.Fatalf("Expected the renamed challenge to win over metadata, got %v", body)
+		}
+		if body["device"] != "ios" {
+			t.Fatalf("Expected metadata field device=ios, got %v", body)
+		}
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			w.Write([]byte(`{"challenge":"test-challenge-123","expires_in":30,"difficulty":4}`))
+		case "/api/challenge/validate":
+			w.Write([]byte(`{"valid":true,"risk_score":0.1}`))
+		}
+	}))
+	defer server.Close()
+
+	t.Run("lenient", func(t *testing.T) {
+		client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+		client.baseURL = server.URL
+
+		if _, err := client.CreateChallenge(30); err != nil {
+			t.Fatalf("Expected unknown fields to be ignored, got %v", err)
+		}
+		if _, err := client.ValidateChallenge("test-challenge-123", "response", nil); err != nil {
+			t.Fatalf("Expected unknown fields to be ignored, got %v", err)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+			StrictDecode: true,
+		})
+		client.baseURL = server.URL
+
+		_, err := client.CreateChallenge(30)
+		if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), "difficulty") {
+			t.Errorf("Expected ErrUnknownField naming difficulty, got %v", err)
+		}
+		_, err = client.ValidateChallenge("test-challenge-123", "response", nil)
+		if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), "risk_score") {
+			t.Errorf("Expected ErrUnknownField naming risk_score, got %v", err)
+		}
+	})
+}
+
+func TestStrictDecode_Quota(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		StrictDecode: true,
+	})
+	client.baseURL = server.URL
+
+	body = `{"valid":true,"quota":{"used":"10","remaining":90,"quota":100,"reset_at":"2030-01-01T00:00:00Z"}}`
+	if _, err := client.ValidateChallenge("test-challenge-123", "response", nil); err != nil {
+		t.Fatalf("Expected a known quota to decode, got %v", err)
+	}
+
+	body = `{"valid":true,"quota":{"used":10,"remaining":90,"quota":100,"bogus":1}}`
+	_, err := client.ValidateChallenge("test-challenge-123", "response", nil)
+	if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), "bogus") {
+		t.Errorf("Expected ErrUnknownField naming bogus, got %v", err)
+	}
+}
+
+func TestBaseURL_TrailingSlash(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	for _, baseURL := range []string{server.URL, server.URL + "/", server.URL + "//"} {
+		paths = nil
+		client, err := NewClientWithConfig(Config{
+			APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+			BaseURL:      baseURL,
+			RequireHTTPS: boolPtr(false),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := client.CreateChallenge(30); err != nil {
+			t.Fatalf("Expected no error for %s, got %v", baseURL, err)
+		}
+		if len(paths) != 1 || paths[0] != "/api/challenge/create" {
+			t.Errorf("Expected path /api/challenge/create for %s, got %v", baseURL, paths)
+		}
+	}
+
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primary.Close() // Unreachable
+
+	paths = nil
+	client, _ := NewClientWithConfig(Config{
+		APIKey:           "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL:          primary.URL + "/",
+		FallbackBaseURLs: []string{server.URL + "/"},
+		RequireHTTPS:     boolPtr(false),
+	})
+	client.ValidateChallenge("test-challenge", "test-response", nil)
+	if len(paths) != 1 || paths[0] != "/api/challenge/validate" {
+		t.Errorf("Expected fallback path /api/challenge/validate, got %v", paths)
+	}
+}
+
+func TestCreateAndValidateBaseURLs(t *testing.T) {
+	var createPaths, validatePaths []string
+	createServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		createPaths = append(createPaths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer createServer.Close()
+
+	validateServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		validatePaths = append(validatePaths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer validateServer.Close()
+
+	client, err := NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL:         "http://unused.invalid",
+		CreateBaseURL:   createServer.URL + "/",
+		ValidateBaseURL: validateServer.URL,
+		RequireHTTPS:    boolPtr(false),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected validation to be valid")
+	}
+
+	if len(createPaths) != 1 || createPaths[0] != "/api/challenge/create" {
+		t.Errorf("Expected the create server to receive the create request, got %v", createPaths)
+	}
+	if len(validatePaths) != 1 || validatePaths[0] != "/api/challenge/validate" {
+		t.Errorf("Expected the validate server to receive the validate request, got %v", validatePaths)
+	}
+}
+
+func TestCreateAndValidateBaseURLs_RequireHTTPS(t *testing.T) {
+	_, err := NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ValidateBaseURL: "http://validate.example.com",
+	})
+	if !errors.Is(err, ErrInsecureBaseURL) {
+		t.Errorf("Expected ErrInsecureBaseURL, got %v", err)
+	}
+}
+
+func TestSetAPIKey(t *testing.T) {
+	var mu sync.Mutex
+	var lastAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		lastAuth = r.Header.Get("Authorization")
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if err := client.SetAPIKey("invalid"); !errors.Is(err, ErrInvalidKeyPrefix) {
+		t.Errorf("Expected ErrInvalidKeyPrefix, got %v", err)
+	}
+	if err := client.SetAPIKey(""); !errors.Is(err, ErrEmptyAPIKey) {
+		t.Errorf("Expected ErrEmptyAPIKey, got %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := client.CreateChallenge(30); err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			if err := client.SetAPIKey(fmt.Sprintf("kc_rotated_%d", i)); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := client.SetAPIKey("kc_latest"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if lastAuth != "Bearer kc_latest" {
+		t.Errorf("Expected the latest key in the Authorization header, got %s", lastAuth)
+	}
+	if fingerprint := client.KeyFingerprint(); !strings.HasPrefix(fingerprint, "kc_...test:") {
+		t.Errorf("Expected the fingerprint of the latest key, got %s", fingerprint)
+	}
+}
@@ -0,0 +1,67 @@
+package keyclaim
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+)
+
+// ClientDiagnostics is a snapshot of a client's configuration that is safe to
+// share in support tickets: it never contains the secret, and the API key is
+// reduced to its first characters.
+type ClientDiagnostics struct {
+	BaseURL           string
+	Timeout           time.Duration // Zero for a Config.Doer that isn't an *http.Client
+	MaxRetries        int
+	RetryBackoff      time.Duration
+	UserAgent         string // The User-Agent the SDK sets, empty as it leaves it to the transport
+	APIKeyPrefix      string
+	CustomSecret      bool // Whether a secret other than the API key was configured
+	AdditionalSecrets int
+	SignRequests      bool
+}
+
+// Diagnostics returns a redacted snapshot of the client's configuration
+func (c *KeyClaimClient) Diagnostics() ClientDiagnostics {
+	apiKey := c.currentAPIKey()
+	var timeout time.Duration
+	if httpClient, ok := c.doer.(*http.Client); ok {
+		timeout = httpClient.Timeout
+	}
+	return ClientDiagnostics{
+		BaseURL:           c.baseURL,
+		Timeout:           timeout,
+		MaxRetries:        c.maxRetries,
+		RetryBackoff:      c.retryBackoff,
+		APIKeyPrefix:      redactAPIKey(apiKey),
+		CustomSecret:      c.customSecret,
+		AdditionalSecrets: len(c.additionalKeys),
+		SignRequests:      c.signRequests,
+	}
+}
+
+// redactAPIKey keeps the "kc_" prefix and the next 4 characters of apiKey
+func redactAPIKey(apiKey string) string {
+	const visible = 7
+	if len(apiKey) <= visible {
+		return apiKey[:min(len(apiKey), 3)] + "..."
+	}
+	return apiKey[:visible] + "..."
+}
+
+// KeyFingerprint returns a short, stable identifier of the API key that is
+// safe to log, of the form "kc_...WXYZ:0123abcd": the key's last 4
+// characters and the first 8 hex characters of its SHA-256 hash
+func (c *KeyClaimClient) KeyFingerprint() string {
+	apiKey := c.currentAPIKey()
+	hash := sha256.Sum256([]byte(apiKey))
+
+	last := apiKey
+	if len(last) > 4+len("kc_") {
+		last = last[len(last)-4:]
+	} else {
+		last = ""
+	}
+	return "kc_..." + last + ":" + hex.EncodeToString(hash[:4])
+}
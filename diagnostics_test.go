@@ -0,0 +1,120 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiagnostics(t *testing.T) {
+	apiKey := "kc_test123456789012345678901234567890123456789012345678901234567890"
+	client, _ := NewClientWithConfig(Config{
+		APIKey:            apiKey,
+		Secret:            "super-secret-value",
+		AdditionalSecrets: []string{"old-secret-value"},
+		MaxRetries:        3,
+		RetryBackoff:      time.Second,
+	})
+
+	diagnostics := client.Diagnostics()
+	if diagnostics.BaseURL != "https://keyclaim.org" {
+		t.Errorf("Expected base URL https://keyclaim.org, got %s", diagnostics.BaseURL)
+	}
+	if diagnostics.Timeout != 30*time.Second {
+		t.Errorf("Expected timeout 30s, got %v", diagnostics.Timeout)
+	}
+	if diagnostics.MaxRetries != 3 || diagnostics.RetryBackoff != time.Second {
+		t.Errorf("Expected retry settings 3/1s, got %d/%v", diagnostics.MaxRetries, diagnostics.RetryBackoff)
+	}
+	if diagnostics.UserAgent != "" {
+		t.Errorf("Expected no user agent, as the SDK doesn't set one, got %q", diagnostics.UserAgent)
+	}
+	if !diagnostics.CustomSecret || diagnostics.AdditionalSecrets != 1 {
+		t.Errorf("Expected custom secret and 1 additional secret, got %+v", diagnostics)
+	}
+	if diagnostics.APIKeyPrefix != "kc_test..." {
+		t.Errorf("Expected API key prefix kc_test..., got %s", diagnostics.APIKeyPrefix)
+	}
+
+	dump := fmt.Sprintf("%+v", diagnostics)
+	for _, secret := range []string{apiKey, "super-secret-value", "old-secret-value"} {
+		if strings.Contains(dump, secret) {
+			t.Errorf("Expected diagnostics not to contain %q, got %s", secret, dump)
+		}
+	}
+}
+
+func TestDiagnostics_DefaultSecret(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	if client.Diagnostics().CustomSecret {
+		t.Error("Expected no custom secret when the secret defaults to the API key")
+	}
+
+	// The secret stays the old key after rotation, which is still not custom
+	client.SetAPIKey("kc_rotated123456789012345678901234567890123456789012345678901234")
+	if client.Diagnostics().CustomSecret {
+		t.Error("Expected no custom secret after rotating the API key")
+	}
+
+	custom, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	custom.SetAPIKey("kc_rotated123456789012345678901234567890123456789012345678901234")
+	if !custom.Diagnostics().CustomSecret {
+		t.Error("Expected a custom secret to be reported after rotating the API key")
+	}
+}
+
+func TestKeyFingerprint(t *testing.T) {
+	apiKey := "kc_test123456789012345678901234567890123456789012345678901234567890"
+	client, _ := NewClient(apiKey)
+	same, _ := NewClientWithSecret(apiKey, "other-secret")
+	other, _ := NewClient("kc_live123456789012345678901234567890123456789012345678901234567890")
+
+	fingerprint := client.KeyFingerprint()
+	if !strings.HasPrefix(fingerprint, "kc_...7890:") || len(fingerprint) != len("kc_...7890:")+8 {
+		t.Errorf("Expected a fingerprint like kc_...7890:xxxxxxxx, got %s", fingerprint)
+	}
+	if strings.Contains(fingerprint, "test1234") {
+		t.Errorf("Expected the fingerprint not to reveal the key, got %s", fingerprint)
+	}
+	if same.KeyFingerprint() != fingerprint {
+		t.Error("Expected the fingerprint to be stable for the same key")
+	}
+	if other.KeyFingerprint() == fingerprint {
+		t.Error("Expected different keys to have different fingerprints")
+	}
+}
+
+func TestIncludeKeyFingerprint(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	for _, include := range []bool{true, false} {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:                "kc_test123456789012345678901234567890123456789012345678901234567890",
+			IncludeKeyFingerprint: include,
+		})
+		client.baseURL = server.URL
+
+		if _, err := client.ValidateChallenge("test-challenge", "test-response", nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		fingerprint, sent := body["key_fingerprint"]
+		if sent != include {
+			t.Errorf("Expected key_fingerprint sent=%v, got body %v", include, body)
+		}
+		if include && fingerprint != client.KeyFingerprint() {
+			t.Errorf("Expected fingerprint %s, got %v", client.KeyFingerprint(), fingerprint)
+		}
+	}
+}
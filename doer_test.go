@@ -0,0 +1,69 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// doerFunc adapts a function to the Doer interface
+type doerFunc func(req *http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+// cannedResponse builds a JSON response without a server
+func cannedResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestDoer(t *testing.T) {
+	var paths []string
+	var validateBody ValidateChallengeOptions
+	doer := doerFunc(func(req *http.Request) (*http.Response, error) {
+		defer req.Body.Close()
+		paths = append(paths, req.URL.Path)
+		if auth := req.Header.Get("Authorization"); auth != "Bearer kc_test123456789012345678901234567890123456789012345678901234567890" {
+			t.Errorf("Expected the API key in the Authorization header, got %s", auth)
+		}
+
+		switch req.URL.Path {
+		case "/api/challenge/create":
+			return cannedResponse(http.StatusOK, `{"challenge":"test-challenge","expires_in":30}`), nil
+		case "/api/challenge/validate":
+			json.NewDecoder(req.Body).Decode(&validateBody)
+			return cannedResponse(http.StatusOK, `{"valid":true}`), nil
+		default:
+			return cannedResponse(http.StatusNotFound, `{"error":"not_found"}`), nil
+		}
+	})
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret: "test-secret",
+		Doer:   doer,
+	})
+
+	result, err := client.Validate(ResponseMethodHMAC, 30, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected the canned validation to be valid")
+	}
+
+	if len(paths) != 2 || paths[0] != "/api/challenge/create" || paths[1] != "/api/challenge/validate" {
+		t.Errorf("Expected create then validate, got %v", paths)
+	}
+	if validateBody.Challenge != "test-challenge" || validateBody.Response != "115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42" {
+		t.Errorf("Unexpected validate request %+v", validateBody)
+	}
+	if client.Diagnostics().Timeout != 0 {
+		t.Error("Expected no timeout to be reported for a custom Doer")
+	}
+}
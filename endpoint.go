@@ -0,0 +1,52 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Do calls an arbitrary API endpoint, for endpoints the SDK doesn't model
+// yet. Requests go through the same pipeline as the built-in methods:
+// authentication, base URL joining, retries, the circuit breaker and the
+// configured hooks. body, when non-nil, is sent as JSON. A 2xx response is
+// decoded into out, when non-nil and the response has a body; other statuses
+// are returned as a *KeyClaimError, matching the sentinel errors as usual.
+func (c *KeyClaimClient) Do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	req, err := c.newRequest(ctx, method, path, body, false)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return c.handleErrorResponse(resp, fmt.Sprintf("Failed to call %s %s", method, path))
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if out == nil || len(bodyBytes) == 0 {
+		return nil
+	}
+
+	if err := checkContentType(resp); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(renameFields(bodyBytes, c.responseFieldMap), out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	type usageRequest struct {
+		Period string `json:"period"`
+	}
+	type usageResponse struct {
+		Requests int    `json:"requests"`
+		Period   string `json:"period"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/usage" {
+			t.Errorf("Expected path /api/usage, got %s", r.URL.Path)
+		}
+		if r.Method != "POST" {
+			t.Errorf("Expected POST method, got %s", r.Method)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Bearer kc_test123456789012345678901234567890123456789012345678901234567890" {
+			t.Errorf("Expected the API key in the Authorization header, got %s", auth)
+		}
+
+		var body usageRequest
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usageResponse{Requests: 42, Period: body.Period})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	var out usageResponse
+	if err := client.Do(context.Background(), "POST", "/api/usage", usageRequest{Period: "month"}, &out); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if out.Requests != 42 || out.Period != "month" {
+		t.Errorf("Unexpected response %+v", out)
+	}
+
+	// A path without a leading slash is joined the same way, and out may be nil
+	if err := client.Do(context.Background(), "POST", "api/usage", nil, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestDo_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error":"quota_exceeded","message":"Monthly quota exhausted"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	var out map[string]interface{}
+	err := client.Do(context.Background(), "GET", "/api/usage", nil, &out)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	var keyclaimErr *KeyClaimError
+	if !errors.As(err, &keyclaimErr) || keyclaimErr.StatusCode != http.StatusPaymentRequired || keyclaimErr.Message != "Monthly quota exhausted" {
+		t.Errorf("Unexpected error %+v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package keyclaim
+
+import "time"
+
+// EventType identifies the kind of an Event
+type EventType string
+
+const (
+	EventChallengeCreated    EventType = "challenge_created"
+	EventValidationSucceeded EventType = "validation_succeeded"
+	EventValidationFailed    EventType = "validation_failed" // Invalid result or error
+	EventRetryScheduled      EventType = "retry_scheduled"
+	EventReplaySuspected     EventType = "replay_suspected" // See Config.ReplayWindow
+)
+
+// Event is published on Config.Events for monitoring. Fields that don't apply
+// to an event type are left zero.
+type Event struct {
+	Type EventType
+	Time time.Time
+
+	Challenge string        // ChallengeCreated and ReplaySuspected
+	ExpiresIn int           // ChallengeCreated
+	Path      string        // RetryScheduled: the API path being retried
+	Attempt   int           // RetryScheduled: the attempt that failed, from 1
+	Delay     time.Duration // RetryScheduled: the backoff before the next attempt
+	Err       error         // ValidationFailed and RetryScheduled, when caused by an error
+}
+
+// emit publishes an event without blocking, dropping it when the events
+// channel is full or unset
+func (c *KeyClaimClient) emit(event Event) {
+	if c.events == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	select {
+	case c.events <- event:
+	default:
+	}
+}
@@ -0,0 +1,94 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	createAttempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			createAttempts++
+			if createAttempts == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+		case "/api/challenge/validate":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	events := make(chan Event, 10)
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+		Events:       events,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	close(events)
+
+	var received []Event
+	for event := range events {
+		received = append(received, event)
+	}
+
+	expected := []EventType{EventRetryScheduled, EventChallengeCreated, EventValidationSucceeded}
+	if len(received) != len(expected) {
+		t.Fatalf("Expected events %v, got %+v", expected, received)
+	}
+	for i, event := range received {
+		if event.Type != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], event.Type)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("Expected event %d to carry a time", i)
+		}
+	}
+
+	if retry := received[0]; retry.Path != "/api/challenge/create" || retry.Attempt != 1 || retry.Delay != time.Millisecond {
+		t.Errorf("Unexpected retry event %+v", retry)
+	}
+	if created := received[1]; created.Challenge != "test-challenge-123" || created.ExpiresIn != 30 {
+		t.Errorf("Unexpected challenge event %+v", created)
+	}
+}
+
+func TestEvents_DroppedWhenFull(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	events := make(chan Event) // Unbuffered and never read
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Events: events,
+	})
+	client.baseURL = server.URL
+
+	done := make(chan struct{})
+	go func() {
+		client.CreateChallenge(30)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected publishing events not to block")
+	}
+}
@@ -0,0 +1,164 @@
+package keyclaim
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureCacheSize = 256
+	defaultFailureCacheTTL  = time.Minute
+)
+
+// FailureCacheConfig configures the cache of recently failed challenges. A
+// challenge the server reported invalid, or rejected with a 4xx such as
+// challenge_expired, isn't sent again for TTL: ValidateChallenge returns the
+// cached outcome instead. Authentication, timeout, rate limit and quota
+// errors, 5xx responses and transport errors are not cached, as they say
+// nothing about the challenge. The cache is keyed on the challenge alone, so a challenge
+// that failed with one response fails with any other until the entry expires.
+type FailureCacheConfig struct {
+	Size int           // Maximum number of challenges remembered, defaults to 256
+	TTL  time.Duration // How long a failure is remembered, defaults to 1 minute
+}
+
+type failureEntry struct {
+	key     [sha256.Size]byte
+	result  *ValidateChallengeResponse
+	err     error
+	expires time.Time
+}
+
+// failureCache is an LRU cache of failed validation outcomes, keyed on the
+// SHA-256 of the challenge so that challenges aren't held in memory
+type failureCache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List // Most recently used at the front
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+func newFailureCache(config *FailureCacheConfig) *failureCache {
+	if config == nil {
+		return nil
+	}
+
+	c := &failureCache{
+		size:    config.Size,
+		ttl:     config.TTL,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+	if c.size <= 0 {
+		c.size = defaultFailureCacheSize
+	}
+	if c.ttl <= 0 {
+		c.ttl = defaultFailureCacheTTL
+	}
+	return c
+}
+
+// lookup returns the cached outcome for challenge, if there is an unexpired
+// one. The entry's result and error are copies, so callers may modify them.
+func (c *failureCache) lookup(challenge string) (failureEntry, bool) {
+	if c == nil {
+		return failureEntry{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[sha256.Sum256([]byte(challenge))]
+	if !ok {
+		return failureEntry{}, false
+	}
+	entry := *element.Value.(*failureEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, entry.key)
+		return failureEntry{}, false
+	}
+
+	c.order.MoveToFront(element)
+	entry.result = copyResult(entry.result)
+	entry.err = copyError(entry.err)
+	return entry, true
+}
+
+// record caches the outcome of validating challenge if it is a failure
+// attributable to the challenge
+func (c *failureCache) record(challenge string, result *ValidateChallengeResponse, err error) {
+	if c == nil || !isChallengeFailure(result, err) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := sha256.Sum256([]byte(challenge))
+	entry := &failureEntry{key: key, result: copyResult(result), err: copyError(err), expires: c.now().Add(c.ttl)}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*failureEntry).key)
+	}
+}
+
+// isChallengeFailure reports whether an outcome is an invalid result or a
+// 4xx rejection of the challenge itself. An exhausted quota isn't: the same
+// challenge may succeed once the quota resets.
+func isChallengeFailure(result *ValidateChallengeResponse, err error) bool {
+	if err == nil {
+		return result != nil && !result.IsValid() &&
+			(result.Error == nil || sentinelFor(*result.Error, 0) != ErrQuotaExceeded)
+	}
+
+	var keyclaimErr *KeyClaimError
+	if !errors.As(err, &keyclaimErr) || errors.Is(err, ErrQuotaExceeded) {
+		return false
+	}
+	switch keyclaimErr.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return keyclaimErr.StatusCode >= 400 && keyclaimErr.StatusCode < 500
+}
+
+// copyResult returns a copy of result, so cached results aren't shared
+func copyResult(result *ValidateChallengeResponse) *ValidateChallengeResponse {
+	if result == nil {
+		return nil
+	}
+	copied := *result
+	return &copied
+}
+
+// copyError returns a copy of the *KeyClaimError in err, so cached errors
+// aren't shared. Only the KeyClaimError is kept, without any wrapping.
+func copyError(err error) error {
+	var keyclaimErr *KeyClaimError
+	if !errors.As(err, &keyclaimErr) {
+		return err
+	}
+	copied := *keyclaimErr
+	if copied.Quota != nil {
+		quota := *copied.Quota
+		copied.Quota = &quota
+	}
+	return &copied
+}
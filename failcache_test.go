@@ -0,0 +1,139 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFailureCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch body.Challenge {
+		case "expired":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"challenge_expired","message":"Challenge expired"}`))
+		case "invalid":
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false)})
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "no-quota":
+			w.WriteHeader(http.StatusPaymentRequired)
+			w.Write([]byte(`{"error":"quota_exceeded","message":"Quota exceeded"}`))
+		case "no-quota-result":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"valid":false,"error":"quota_exceeded"}`))
+		default:
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+		}
+	}))
+	defer server.Close()
+
+	newClient := func(config *FailureCacheConfig) *KeyClaimClient {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+			FailureCache: config,
+		})
+		client.baseURL = server.URL
+		return client
+	}
+
+	t.Run("cached error", func(t *testing.T) {
+		requests = 0
+		client := newClient(&FailureCacheConfig{})
+
+		_, first := client.ValidateChallenge("expired", "test-response", nil)
+		_, second := client.ValidateChallenge("expired", "other-response", nil)
+		if !errors.Is(first, ErrChallengeExpired) || !errors.Is(second, ErrChallengeExpired) {
+			t.Fatalf("Expected ErrChallengeExpired twice, got %v and %v", first, second)
+		}
+		if requests != 1 {
+			t.Errorf("Expected the second validation to be served from the cache, got %d requests", requests)
+		}
+
+		// Each caller gets its own copy of the cached error
+		var firstErr, secondErr *KeyClaimError
+		errors.As(first, &firstErr)
+		errors.As(second, &secondErr)
+		_, third := client.ValidateChallenge("expired", "test-response", nil)
+		secondErr.Message = "modified"
+		if firstErr == secondErr || third.Error() != "Challenge expired" {
+			t.Errorf("Expected cached errors not to be shared, got %v", third)
+		}
+	})
+
+	t.Run("cached invalid result", func(t *testing.T) {
+		requests = 0
+		client := newClient(&FailureCacheConfig{})
+
+		first, _ := client.ValidateChallenge("invalid", "test-response", nil)
+		first.Valid = boolPtr(true)
+		second, err := client.ValidateChallenge("invalid", "test-response", nil)
+		if err != nil || second.IsValid() {
+			t.Fatalf("Expected the cached invalid result, got %+v, %v", second, err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected 1 request, got %d", requests)
+		}
+	})
+
+	t.Run("not cached", func(t *testing.T) {
+		requests = 0
+		client := newClient(&FailureCacheConfig{})
+
+		for i := 0; i < 2; i++ {
+			client.ValidateChallenge("unavailable", "test-response", nil)
+			client.ValidateChallenge("valid", "test-response", nil)
+			client.ValidateChallenge("no-quota", "test-response", nil)
+			client.ValidateChallenge("no-quota-result", "test-response", nil)
+		}
+		if requests != 8 {
+			t.Errorf("Expected 5xx, quota and valid outcomes not to be cached, got %d requests", requests)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		requests = 0
+		client := newClient(&FailureCacheConfig{TTL: time.Minute})
+		now := time.Now()
+		client.failures.now = func() time.Time { return now }
+
+		client.ValidateChallenge("expired", "test-response", nil)
+		now = now.Add(time.Minute)
+		client.ValidateChallenge("expired", "test-response", nil)
+		if requests != 2 {
+			t.Errorf("Expected the entry to expire after the TTL, got %d requests", requests)
+		}
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		requests = 0
+		client := newClient(&FailureCacheConfig{Size: 1})
+
+		client.ValidateChallenge("expired", "test-response", nil)
+		client.ValidateChallenge("invalid", "test-response", nil)
+		client.ValidateChallenge("expired", "test-response", nil)
+		if requests != 3 {
+			t.Errorf("Expected the least recently used entry to be evicted, got %d requests", requests)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		requests = 0
+		client := newClient(nil)
+
+		client.ValidateChallenge("expired", "test-response", nil)
+		client.ValidateChallenge("expired", "test-response", nil)
+		if requests != 2 {
+			t.Errorf("Expected no caching by default, got %d requests", requests)
+		}
+	})
+}
@@ -2,3 +2,6 @@ module github.com/creasoftlb/keyclaim-go-sdk
 
 go 1.21
 
+require golang.org/x/crypto v0.33.0
+
+require golang.org/x/sys v0.30.0 // indirect
@@ -0,0 +1,69 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// HashAlgorithm is the digest behind the hmac and hash response methods
+type HashAlgorithm string
+
+const (
+	// HashSHA256 is the default, giving 64 hex characters
+	HashSHA256 HashAlgorithm = "sha256"
+	// HashSHA512 gives 128 hex characters
+	HashSHA512 HashAlgorithm = "sha512"
+	// HashBLAKE2b256 is BLAKE2b with a 256-bit digest, giving 64 hex characters
+	HashBLAKE2b256 HashAlgorithm = "blake2b-256"
+	// HashSHA3_256 is SHA3-256, giving 64 hex characters
+	HashSHA3_256 HashAlgorithm = "sha3-256"
+)
+
+// newHashFunc returns the constructor for algorithm, or nil for SHA-256 so
+// that the pooled fast paths are used
+func newHashFunc(algorithm HashAlgorithm) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", HashSHA256:
+		return nil, nil
+	case HashSHA512:
+		return sha512.New, nil
+	case HashBLAKE2b256:
+		return func() hash.Hash {
+			h, _ := blake2b.New256(nil) // Only fails for keys over 64 bytes
+			return h
+		}, nil
+	case HashSHA3_256:
+		return sha3.New256, nil
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm: %s", algorithm)
+	}
+}
+
+// hmacHex returns hex(HMAC(key, message)) with the configured algorithm
+func (c *KeyClaimClient) hmacHex(key []byte, message string) string {
+	if c.newHash == nil {
+		return c.hmacSHA256(key, message)
+	}
+
+	h := hmac.New(c.newHash, key)
+	h.Write([]byte(message))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashHex returns hex(H(message + suffix)) with the configured algorithm
+func (c *KeyClaimClient) hashHex(message string, suffix []byte) string {
+	if c.newHash == nil {
+		return sha256Hex(message, suffix)
+	}
+
+	h := c.newHash()
+	h.Write([]byte(message))
+	h.Write(suffix)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,72 @@
+package keyclaim
+
+import "testing"
+
+func TestHashAlgorithm(t *testing.T) {
+	// Vectors for challenge "test-challenge" and secret "test-secret",
+	// computed independently with Python's hashlib and hmac
+	tests := []struct {
+		algorithm HashAlgorithm
+		hmac      string
+		hash      string
+	}{
+		{
+			HashSHA256,
+			"115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42",
+			"",
+		},
+		{
+			HashSHA512,
+			"888be8f7c213397dc53bb1148e8787ec4801d0d9a1b8a30e06bf7d33cfa01a8fb4827057200d91ab68c7a9d0d4ff918e4bca61d8bd8772f72236fd4406438fe3",
+			"50184dac5d0b55077a18e471201a2e0894c610771660bb2c7afaebe3b1c8d1d588c228995d2bfbdbeaff1b2b72dc9d6fd837dabc2ff8d5e51224ae31a212911d",
+		},
+		{
+			HashBLAKE2b256,
+			"edce9d2e270a4ec08971efe0d5fb661b7c93dd4130531341bf05c7029e185169",
+			"5d55e3fa1c73e017008953b5451b2dde5979537dabad77fdc1eb9f95eea70365",
+		},
+		{
+			HashSHA3_256,
+			"61607428feeeca783f329f89360d5f7d7db799dcdd5403e316758091371ba14c",
+			"82dc47daae3a684a30d32de2e2f9b88af84815c1d0321a8baa1d883cb00ece5d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.algorithm), func(t *testing.T) {
+			client, err := NewClientWithConfig(Config{
+				APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+				Secret:        "test-secret",
+				HashAlgorithm: tt.algorithm,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			response, _ := client.GenerateResponse("test-challenge", ResponseMethodHMAC, nil)
+			if response != tt.hmac {
+				t.Errorf("Expected hmac %s, got %s", tt.hmac, response)
+			}
+			if tt.hash != "" {
+				response, _ = client.GenerateResponse("test-challenge", ResponseMethodHash, nil)
+				if response != tt.hash {
+					t.Errorf("Expected hash %s, got %s", tt.hash, response)
+				}
+			}
+
+			if valid, _ := client.VerifyResponse("test-challenge", tt.hmac, ResponseMethodHMAC, nil); !valid {
+				t.Error("Expected VerifyResponse to use the configured algorithm")
+			}
+		})
+	}
+}
+
+func TestHashAlgorithm_Unknown(t *testing.T) {
+	_, err := NewClientWithConfig(Config{
+		APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+		HashAlgorithm: "md5",
+	})
+	if err == nil {
+		t.Error("Expected error for an unknown hash algorithm")
+	}
+}
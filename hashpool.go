@@ -0,0 +1,66 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"sync"
+)
+
+// pooledHash is a hash state together with a buffer for its digest, so that
+// neither needs allocating per call
+type pooledHash struct {
+	hash.Hash
+	sum [sha256.Size]byte
+}
+
+// sha256Pool recycles SHA-256 states for the hash and custom methods
+var sha256Pool = sync.Pool{
+	New: func() any { return &pooledHash{Hash: sha256.New()} },
+}
+
+// newHMACPool returns a pool of HMAC-SHA256 states keyed with key. HMAC
+// setup hashes the key twice, so reusing states saves that work as well as
+// the allocations.
+func newHMACPool(key []byte) *sync.Pool {
+	key = append([]byte(nil), key...)
+	return &sync.Pool{
+		New: func() any { return &pooledHash{Hash: hmac.New(sha256.New, key)} },
+	}
+}
+
+// hmacSHA256 returns hex(HMAC-SHA256(key, message)), using the client's
+// pooled states when key is the primary key
+func (c *KeyClaimClient) hmacSHA256(key []byte, message string) string {
+	if c.hmacPool == nil || !hmac.Equal(key, c.key) {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(message))
+		return hex.EncodeToString(h.Sum(nil))
+	}
+
+	h := c.hmacPool.Get().(*pooledHash)
+	defer c.hmacPool.Put(h)
+	h.Reset()
+
+	h.Write([]byte(message))
+	return sumHex(h)
+}
+
+// sha256Hex returns hex(SHA-256(message + suffix)), using a pooled state
+func sha256Hex(message string, suffix []byte) string {
+	h := sha256Pool.Get().(*pooledHash)
+	defer sha256Pool.Put(h)
+	h.Reset()
+
+	h.Write([]byte(message))
+	h.Write(suffix)
+	return sumHex(h)
+}
+
+// sumHex returns the hex-encoded digest of h
+func sumHex(h *pooledHash) string {
+	var encoded [2 * sha256.Size]byte
+	hex.Encode(encoded[:], h.Sum(h.sum[:0]))
+	return string(encoded[:])
+}
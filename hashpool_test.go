@@ -0,0 +1,79 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestGenerateResponse_Concurrent(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				challenge := fmt.Sprintf("challenge-%d-%d", g, i)
+
+				h := hmac.New(sha256.New, []byte("test-secret"))
+				h.Write([]byte(challenge))
+				expectedHMAC := hex.EncodeToString(h.Sum(nil))
+				sum := sha256.Sum256([]byte(challenge + "test-secret"))
+				expectedHash := hex.EncodeToString(sum[:])
+
+				if got, _ := client.GenerateResponse(challenge, ResponseMethodHMAC, nil); got != expectedHMAC {
+					errs <- fmt.Errorf("hmac for %s: expected %s, got %s", challenge, expectedHMAC, got)
+					return
+				}
+				if got, _ := client.GenerateResponse(challenge, ResponseMethodHash, nil); got != expectedHash {
+					errs <- fmt.Errorf("hash for %s: expected %s, got %s", challenge, expectedHash, got)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func BenchmarkGenerateResponse(b *testing.B) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	methods := []struct {
+		method     ResponseMethod
+		customData interface{}
+	}{
+		{ResponseMethodHMAC, nil},
+		{ResponseMethodHash, nil},
+		{ResponseMethodCustom, "custom-string"},
+	}
+	for _, m := range methods {
+		b.Run(string(m.method), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := client.GenerateResponse("test-challenge-123", m.method, m.customData); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+
+	b.Run("hmac/parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				client.GenerateResponse("test-challenge-123", ResponseMethodHMAC, nil)
+			}
+		})
+	})
+}
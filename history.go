@@ -0,0 +1,60 @@
+package keyclaim
+
+import (
+	"sync"
+	"time"
+)
+
+// RequestRecord is an entry of the request history kept when
+// Config.HistorySize is set. Authorization is redacted, as for DebugHook.
+type RequestRecord struct {
+	DebugExchange
+	Time  time.Time // When the exchange completed
+	Error string    // Transport error, when no response was received
+}
+
+// requestHistory is a fixed-size ring buffer of the most recent requests
+type requestHistory struct {
+	mu      sync.Mutex
+	records []RequestRecord
+	next    int
+	full    bool
+}
+
+func newRequestHistory(size int) *requestHistory {
+	if size <= 0 {
+		return nil
+	}
+	return &requestHistory{records: make([]RequestRecord, size)}
+}
+
+func (h *requestHistory) add(record RequestRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// snapshot returns the recorded requests, oldest first
+func (h *requestHistory) snapshot() []RequestRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]RequestRecord(nil), h.records[:h.next]...)
+	}
+	return append(append([]RequestRecord(nil), h.records[h.next:]...), h.records[:h.next]...)
+}
+
+// History returns the most recent requests, oldest first, up to
+// Config.HistorySize of them. It returns nil when the history is disabled.
+func (c *KeyClaimClient) History() []RequestRecord {
+	if c.history == nil {
+		return nil
+	}
+	return c.history.snapshot()
+}
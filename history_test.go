@@ -0,0 +1,71 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHistory(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/api/challenge/validate" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_api_key"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:      "kc_test123456789012345678901234567890123456789012345678901234567890",
+		HistorySize: 2,
+	})
+	client.baseURL = server.URL
+
+	client.CreateChallenge(30)
+	client.CreateChallenge(45)
+	client.ValidateChallenge("test-challenge", "test-response", nil)
+
+	history := client.History()
+	if len(history) != 2 {
+		t.Fatalf("Expected the last 2 requests to be recorded, got %d", len(history))
+	}
+
+	if history[0].URL != server.URL+"/api/challenge/create" || history[0].StatusCode != http.StatusOK {
+		t.Errorf("Expected a 200 create first, got %d %s", history[0].StatusCode, history[0].URL)
+	}
+	if string(history[0].RequestBody) != `{"ttl":45}` {
+		t.Errorf("Expected the second create to be kept, got body %s", history[0].RequestBody)
+	}
+	if history[1].URL != server.URL+"/api/challenge/validate" || history[1].StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected a 401 validate last, got %d %s", history[1].StatusCode, history[1].URL)
+	}
+	if auth := history[1].RequestHeader.Get("Authorization"); auth != "[REDACTED]" {
+		t.Errorf("Expected Authorization to be redacted, got %s", auth)
+	}
+}
+
+func TestHistory_TransportError(t *testing.T) {
+	client, _ := NewClientWithConfig(Config{
+		APIKey:      "kc_test123456789012345678901234567890123456789012345678901234567890",
+		HistorySize: 5,
+	})
+	client.baseURL = "http://127.0.0.1:0"
+
+	client.CreateChallenge(30)
+
+	history := client.History()
+	if len(history) != 1 || history[0].Error == "" || history[0].StatusCode != 0 {
+		t.Errorf("Expected one record carrying the transport error, got %+v", history)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	if history := client.History(); history != nil {
+		t.Errorf("Expected no history, got %v", history)
+	}
+}
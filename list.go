@@ -0,0 +1,93 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ChallengeInfo describes an active challenge, as returned by ListChallenges
+type ChallengeInfo struct {
+	Challenge   string     `json:"challenge"`
+	ChallengeID string     `json:"id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// challengePage is one page of the listing endpoint's response
+type challengePage struct {
+	Challenges []ChallengeInfo `json:"challenges"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// ListChallenges returns the challenges that are active for the API key, that
+// is unused and unexpired, for administrative tooling. Pages are followed
+// through the server's cursor until the last one. Servers without the listing
+// endpoint yield an error matching ErrNotSupported.
+func (c *KeyClaimClient) ListChallenges(ctx context.Context) ([]ChallengeInfo, error) {
+	var challenges []ChallengeInfo
+	seen := make(map[string]bool)
+
+	cursor := ""
+	for {
+		page, err := c.listChallengePage(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		challenges = append(challenges, page.Challenges...)
+
+		if page.NextCursor == "" {
+			return challenges, nil
+		}
+		// A server handing out a cursor twice would otherwise loop forever
+		if seen[page.NextCursor] {
+			return nil, fmt.Errorf("failed to list challenges: cursor %q repeated", page.NextCursor)
+		}
+		seen[page.NextCursor] = true
+		cursor = page.NextCursor
+	}
+}
+
+func (c *KeyClaimClient) listChallengePage(ctx context.Context, cursor string) (*challengePage, error) {
+	path := "/api/challenge/list"
+	if cursor != "" {
+		path += "?cursor=" + url.QueryEscape(cursor)
+	}
+
+	req, err := c.newRequest(ctx, "GET", path, nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list challenges: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%w: challenge listing", ErrNotSupported)
+	default:
+		return nil, c.handleErrorResponse(resp, "Failed to list challenges")
+	}
+
+	if err := checkContentType(resp); err != nil {
+		return nil, err
+	}
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var page challengePage
+	if err := json.Unmarshal(renameFields(bodyBytes, c.responseFieldMap), &page); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,72 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListChallenges(t *testing.T) {
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "GET" || r.URL.Path != "/api/challenge/list" {
+			t.Errorf("Expected GET /api/challenge/list, got %s %s", r.Method, r.URL.Path)
+		}
+		cursor := r.URL.Query().Get("cursor")
+		cursors = append(cursors, cursor)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch cursor {
+		case "":
+			w.Write([]byte(`{"challenges":[{"challenge":"c1","id":"id-1"},{"challenge":"c2"}],"next_cursor":"page 2"}`))
+		case "page 2":
+			w.Write([]byte(`{"challenges":[{"challenge":"c3","expires_at":"2026-01-01T00:00:00Z"}]}`))
+		default:
+			t.Errorf("Unexpected cursor %q", cursor)
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	challenges, err := client.ListChallenges(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(challenges) != 3 || challenges[0].ChallengeID != "id-1" || challenges[2].Challenge != "c3" || challenges[2].ExpiresAt == nil {
+		t.Errorf("Unexpected challenges %+v", challenges)
+	}
+	if len(cursors) != 2 || cursors[1] != "page 2" {
+		t.Errorf("Expected the cursor to be followed, got %q", cursors)
+	}
+}
+
+func TestListChallenges_RepeatedCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"challenges":[{"challenge":"c1"}],"next_cursor":"again"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if _, err := client.ListChallenges(context.Background()); err == nil {
+		t.Error("Expected error for a repeated cursor")
+	}
+}
+
+func TestListChallenges_NotSupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if _, err := client.ListChallenges(context.Background()); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("Expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package keyclaim
+
+import (
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Logger is the logging interface accepted by Config.Logger. Key-value pairs
+// follow the slog convention of alternating keys and values, so *slog.Logger
+// implements it directly; adapters for logr, zap and others only need to
+// forward the four methods.
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// NewSlogLogger adapts l to Logger, using slog.Default() when l is nil
+func NewSlogLogger(l *slog.Logger) Logger {
+	if l == nil {
+		l = slog.Default()
+	}
+	return l
+}
+
+// redacted replaces secrets in log records
+const redacted = "[REDACTED]"
+
+// sensitiveLogKeys are log keys whose values are always redacted
+var sensitiveLogKeys = []string{"authorization", "api_key", "apikey", "secret", "password", "token"}
+
+// redactingLogger wraps the configured Logger so that no adapter ever sees
+// the API key or secret, whether passed under a sensitive key or embedded in
+// a message or value
+type redactingLogger struct {
+	logger Logger
+
+	mu      sync.RWMutex // Guards secrets, which grow when the API key rotates
+	secrets []string
+}
+
+// newRedactingLogger wraps logger, redacting the given secrets. It returns
+// nil when logger is nil, so that logging stays disabled.
+func newRedactingLogger(logger Logger, secrets ...string) Logger {
+	if l, ok := logger.(*slog.Logger); logger == nil || ok && l == nil {
+		return nil
+	}
+
+	r := &redactingLogger{logger: logger}
+	for _, secret := range secrets {
+		r.addSecret(secret)
+	}
+	return r
+}
+
+// addSecret redacts secret from future records as well
+func (r *redactingLogger) addSecret(secret string) {
+	// Very short secrets would redact unrelated text
+	if len(secret) < 8 {
+		return
+	}
+
+	r.mu.Lock()
+	r.secrets = append(r.secrets, secret)
+	r.mu.Unlock()
+}
+
+func (r *redactingLogger) Debug(msg string, keysAndValues ...any) {
+	r.logger.Debug(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Info(msg string, keysAndValues ...any) {
+	r.logger.Info(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Warn(msg string, keysAndValues ...any) {
+	r.logger.Warn(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+func (r *redactingLogger) Error(msg string, keysAndValues ...any) {
+	r.logger.Error(r.scrub(msg), r.redact(keysAndValues)...)
+}
+
+// redact returns a copy of keysAndValues with sensitive values replaced
+func (r *redactingLogger) redact(keysAndValues []any) []any {
+	out := make([]any, len(keysAndValues))
+	for i, v := range keysAndValues {
+		if i%2 == 1 {
+			if key, ok := keysAndValues[i-1].(string); ok && isSensitiveLogKey(key) {
+				out[i] = redacted
+				continue
+			}
+		}
+
+		switch v := v.(type) {
+		case string:
+			out[i] = r.scrub(v)
+		case error:
+			if scrubbed := r.scrub(v.Error()); scrubbed != v.Error() {
+				out[i] = scrubbed
+			} else {
+				out[i] = v
+			}
+		default:
+			out[i] = v
+		}
+	}
+	return out
+}
+
+// scrub replaces occurrences of the secrets in s
+func (r *redactingLogger) scrub(s string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return s
+}
+
+func isSensitiveLogKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveLogKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
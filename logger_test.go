@@ -0,0 +1,156 @@
+package keyclaim
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeLogger records log calls as "LEVEL msg key=value ..."
+type fakeLogger struct {
+	mu      sync.Mutex
+	records []string
+}
+
+func (l *fakeLogger) log(level, msg string, keysAndValues []any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record := level + " " + msg
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		record += fmt.Sprintf(" %v=%v", keysAndValues[i], keysAndValues[i+1])
+	}
+	l.records = append(l.records, record)
+}
+
+func (l *fakeLogger) Debug(msg string, keysAndValues ...any) { l.log("DEBUG", msg, keysAndValues) }
+func (l *fakeLogger) Info(msg string, keysAndValues ...any)  { l.log("INFO", msg, keysAndValues) }
+func (l *fakeLogger) Warn(msg string, keysAndValues ...any)  { l.log("WARN", msg, keysAndValues) }
+func (l *fakeLogger) Error(msg string, keysAndValues ...any) { l.log("ERROR", msg, keysAndValues) }
+
+func TestLogger_WarnsOnServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	logger := &fakeLogger{}
+	client, _ := NewClientWithConfig(Config{
+		APIKey: "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret: "test-secret",
+		Logger: logger,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err == nil {
+		t.Fatal("Expected error for a 500 response")
+	}
+
+	if len(logger.records) != 1 {
+		t.Fatalf("Expected 1 log record, got %v", logger.records)
+	}
+	if record := logger.records[0]; !strings.HasPrefix(record, "WARN ") || !strings.Contains(record, "status=500") {
+		t.Errorf("Expected a warning with status=500, got %q", record)
+	}
+}
+
+func TestLogger_Redaction(t *testing.T) {
+	const apiKey = "kc_test123456789012345678901234567890123456789012345678901234567890"
+
+	logger := &fakeLogger{}
+	redacting := newRedactingLogger(logger, apiKey, "super-secret-value", "short")
+
+	redacting.Warn("sending "+apiKey,
+		"authorization", "Bearer xyz",
+		"url", "https://example.com/?key="+apiKey,
+		"error", errors.New("bad secret super-secret-value"),
+		"path", "/api/challenge/create",
+		"short", "short",
+	)
+
+	record := logger.records[0]
+	for _, leaked := range []string{apiKey, "super-secret-value", "Bearer xyz"} {
+		if strings.Contains(record, leaked) {
+			t.Errorf("Expected %q to be redacted, got %q", leaked, record)
+		}
+	}
+	for _, kept := range []string{"path=/api/challenge/create", "short=short"} {
+		if !strings.Contains(record, kept) {
+			t.Errorf("Expected %q to be kept, got %q", kept, record)
+		}
+	}
+}
+
+func TestNewSlogLogger(t *testing.T) {
+	var logs bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	redacting := newRedactingLogger(logger, "kc_test123456789012345678901234567890123456789012345678901234567890")
+	redacting.Warn("keyclaim: test", "api_key", "kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	if out := logs.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, "api_key="+redacted) {
+		t.Errorf("Expected a redacted warning, got %q", out)
+	}
+
+	if NewSlogLogger(nil) == nil {
+		t.Error("Expected NewSlogLogger(nil) to fall back to slog.Default()")
+	}
+	if newRedactingLogger((*slog.Logger)(nil)) != nil {
+		t.Error("Expected a nil *slog.Logger to disable logging")
+	}
+}
+
+func TestLogger_SharedSecretWarning(t *testing.T) {
+	const apiKey = "kc_test123456789012345678901234567890123456789012345678901234567890"
+
+	tests := []struct {
+		name   string
+		config Config
+		warned bool
+	}{
+		{"default secret", Config{APIKey: apiKey}, true},
+		{"explicit api key", Config{APIKey: apiKey, Secret: apiKey}, true},
+		{"separate secret", Config{APIKey: apiKey, Secret: "test-secret"}, false},
+		{"disabled", Config{APIKey: apiKey, WarnOnSharedSecret: boolPtr(false)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &fakeLogger{}
+			tt.config.Logger = logger
+			if _, err := NewClientWithConfig(tt.config); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			warned := len(logger.records) == 1 && strings.Contains(logger.records[0], "WARN keyclaim: the secret is the API key")
+			if warned != tt.warned {
+				t.Errorf("Expected warned=%v, got records %v", tt.warned, logger.records)
+			}
+			for _, record := range logger.records {
+				if strings.Contains(record, apiKey) {
+					t.Errorf("Expected the API key to be redacted, got %q", record)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireExplicitSecret(t *testing.T) {
+	const apiKey = "kc_test123456789012345678901234567890123456789012345678901234567890"
+
+	if _, err := NewClientWithConfig(Config{APIKey: apiKey, RequireExplicitSecret: true}); !errors.Is(err, ErrSecretRequired) {
+		t.Errorf("Expected ErrSecretRequired, got %v", err)
+	}
+	if _, err := NewClientWithConfig(Config{APIKey: apiKey, Secret: apiKey, RequireExplicitSecret: true}); err != nil {
+		t.Errorf("Expected an explicit secret equal to the API key to be accepted, got %v", err)
+	}
+	if _, err := NewClientWithConfig(Config{APIKey: apiKey, SecretBytes: []byte{1, 2, 3}, RequireExplicitSecret: true}); err != nil {
+		t.Errorf("Expected SecretBytes to count as explicit, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ChallengeExtractor pulls the challenge and response to validate from an
+// incoming request, e.g. from headers or form values
+type ChallengeExtractor func(r *http.Request) (challenge, response string, err error)
+
+type validationResultKey struct{}
+
+// VerifyMiddleware returns net/http middleware that validates the challenge
+// and response extracted from each request before passing it on. Requests
+// are rejected with 401 when extraction fails, validation doesn't succeed
+// (see KeyClaimClient.Succeeded), the API turns the submission down with a
+// 4xx (an expired challenge, for instance) or the response was replayed, with
+// 503 when the client holds calls back (ErrCircuitOpen, ErrConcurrencyLimit)
+// and with 502 when the KeyClaim API can't be reached or fails with a 5xx. The validation result is
+// available to the next handler through ValidationResultFromContext.
+func VerifyMiddleware(client *KeyClaimClient, extract ChallengeExtractor) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			challenge, response, err := extract(r)
+			if err != nil || challenge == "" || response == "" {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			result, err := client.ValidateChallengeContext(r.Context(), challenge, response, nil)
+			if err != nil && !errors.Is(err, ErrValidationFailed) {
+				status := errorStatus(err)
+				http.Error(w, http.StatusText(status), status)
+				return
+			}
+			if !client.Succeeded(result) {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), validationResultKey{}, result)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// errorStatus maps a validation error to the status VerifyMiddleware rejects
+// the request with
+func errorStatus(err error) int {
+	var apiErr *KeyClaimError
+	switch {
+	case errors.Is(err, ErrReplayDetected),
+		errors.Is(err, ErrChallengeExpired),
+		errors.Is(err, ErrAmbiguousValidation):
+		return http.StatusUnauthorized
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 400 && apiErr.StatusCode < 500:
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrCircuitOpen), errors.Is(err, ErrConcurrencyLimit):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+// ValidationResultFromContext returns the validation result stored by
+// VerifyMiddleware, or nil outside of it
+func ValidationResultFromContext(ctx context.Context) *ValidateChallengeResponse {
+	result, _ := ctx.Value(validationResultKey{}).(*ValidateChallengeResponse)
+	return result
+}
@@ -0,0 +1,227 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func headerExtractor(r *http.Request) (string, string, error) {
+	return r.Header.Get("X-KeyClaim-Challenge"), r.Header.Get("X-KeyClaim-Response"), nil
+}
+
+func TestVerifyMiddleware(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Response != "good-response" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("Invalid response")})
+			return
+		}
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer api.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = api.URL
+
+	var reached bool
+	handler := VerifyMiddleware(client, headerExtractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		if !ValidationResultFromContext(r.Context()).IsValid() {
+			t.Error("Expected the validation result in the request context")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		reached  bool
+	}{
+		{"valid", "good-response", http.StatusNoContent, true},
+		{"invalid", "bad-response", http.StatusUnauthorized, false},
+		{"missing", "", http.StatusUnauthorized, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reached = false
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("X-KeyClaim-Challenge", "test-challenge")
+			req.Header.Set("X-KeyClaim-Response", tt.response)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+			if reached != tt.reached {
+				t.Errorf("Expected next handler reached = %v, got %v", tt.reached, reached)
+			}
+		})
+	}
+}
+
+func TestVerifyMiddleware_APIUnavailable(t *testing.T) {
+	api := httptest.NewServer(http.NotFoundHandler())
+	api.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = api.URL
+
+	handler := VerifyMiddleware(client, headerExtractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected next handler not to be reached")
+	}))
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("X-KeyClaim-Challenge", "test-challenge")
+	req.Header.Set("X-KeyClaim-Response", "good-response")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", rec.Code)
+	}
+}
+
+func TestVerifyMiddleware_ErrorOnInvalid(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("Invalid response")})
+	}))
+	defer api.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ErrorOnInvalid: true,
+	})
+	client.baseURL = api.URL
+
+	handler := VerifyMiddleware(client, headerExtractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected the next handler not to be reached")
+	}))
+
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("X-KeyClaim-Challenge", "test-challenge")
+	req.Header.Set("X-KeyClaim-Response", "bad-response")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an invalid result, got %d", rec.Code)
+	}
+}
+
+func TestVerifyMiddleware_ClientRefusals(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer api.Close()
+
+	serve := func(client *KeyClaimClient) int {
+		handler := VerifyMiddleware(client, headerExtractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("X-KeyClaim-Challenge", "test-challenge")
+		req.Header.Set("X-KeyClaim-Response", "good-response")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+			ReplayWindow:  time.Minute,
+			RejectReplays: true,
+		})
+		client.baseURL = api.URL
+
+		if status := serve(client); status != http.StatusNoContent {
+			t.Fatalf("Expected status 204 for the first submission, got %d", status)
+		}
+		if status := serve(client); status != http.StatusUnauthorized {
+			t.Errorf("Expected status 401 for a replay, got %d", status)
+		}
+	})
+
+	t.Run("concurrency limit", func(t *testing.T) {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:                "kc_test123456789012345678901234567890123456789012345678901234567890",
+			MaxConcurrency:        1,
+			FailFastOnConcurrency: true,
+		})
+		client.baseURL = api.URL
+		client.slots <- struct{}{} // Occupy the only slot
+
+		if status := serve(client); status != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", status)
+		}
+	})
+
+	t.Run("circuit open", func(t *testing.T) {
+		client, _ := NewClientWithConfig(Config{
+			APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+			CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 1},
+		})
+		client.baseURL = api.URL
+		client.breaker.open = true
+		client.breaker.openedAt = time.Now()
+
+		if status := serve(client); status != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", status)
+		}
+	})
+}
+
+func TestVerifyMiddleware_APIRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   int
+	}{
+		{"expired challenge", http.StatusBadRequest, `{"error":"challenge_expired"}`, http.StatusUnauthorized},
+		{"not found", http.StatusNotFound, `{"error":"Challenge not found"}`, http.StatusUnauthorized},
+		{"server error", http.StatusInternalServerError, `{"error":"Internal error"}`, http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer api.Close()
+
+			client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+			client.baseURL = api.URL
+
+			handler := VerifyMiddleware(client, headerExtractor)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("Expected the next handler not to be reached")
+			}))
+
+			req := httptest.NewRequest("GET", "/protected", nil)
+			req.Header.Set("X-KeyClaim-Challenge", "test-challenge")
+			req.Header.Set("X-KeyClaim-Response", "good-response")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
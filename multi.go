@@ -0,0 +1,63 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// validateMultiRequest is the validate request body for ValidateMulti
+type validateMultiRequest struct {
+	Challenge      string                    `json:"challenge"`
+	Responses      map[ResponseMethod]string `json:"responses"`
+	KeyFingerprint string                    `json:"key_fingerprint,omitempty"`
+}
+
+// ValidateMulti validates several responses to one challenge, one per
+// method, for protocols that require proving possession in more than one
+// way. All responses go to the validate endpoint in a single request:
+//
+//	{"challenge": "...", "responses": {"hmac": "...", "hash": "..."}}
+//
+// and the server reports a verdict per method in Results:
+//
+//	{"valid": true, "results": {"hmac": true, "hash": true}}
+//
+// The returned result is valid only when Valid is true and every response
+// was confirmed; a method missing from Results counts as unconfirmed.
+// Servers that answer without Results are trusted on Valid alone.
+func (c *KeyClaimClient) ValidateMulti(challenge string, responses map[ResponseMethod]string) (*ValidateChallengeResponse, error) {
+	return c.ValidateMultiContext(context.Background(), challenge, responses)
+}
+
+// ValidateMultiContext validates several responses like ValidateMulti,
+// honoring ctx cancellation
+func (c *KeyClaimClient) ValidateMultiContext(ctx context.Context, challenge string, responses map[ResponseMethod]string) (*ValidateChallengeResponse, error) {
+	if len(responses) == 0 {
+		return nil, errors.New("no responses to validate")
+	}
+
+	body := validateMultiRequest{
+		Challenge: challenge,
+		Responses: responses,
+	}
+	if c.includeKeyFingerprint {
+		body.KeyFingerprint = c.KeyFingerprint()
+	}
+
+	resp, err := c.doWithFallback(ctx, "POST", "/api/challenge/validate", body, nil)
+	if err != nil {
+		return c.finishValidation(nil, fmt.Errorf("failed to validate challenge: %w", err))
+	}
+	defer resp.Body.Close()
+
+	result, err := c.decodeValidationResponse(resp, "Failed to validate challenge")
+	if err == nil && result.Results != nil {
+		valid := result.IsValid()
+		for method := range responses {
+			valid = valid && result.Results[method]
+		}
+		result.Valid = &valid
+	}
+	return c.finishValidation(result, err)
+}
@@ -0,0 +1,78 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateMulti(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	var received validateMultiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = validateMultiRequest{}
+		json.NewDecoder(r.Body).Decode(&received)
+
+		// Confirm each response, except that the hash one is skipped for
+		// the "partial" challenge
+		results := make(map[ResponseMethod]bool)
+		for method, response := range received.Responses {
+			if method == ResponseMethodHash && received.Challenge == "partial" {
+				continue
+			}
+			expected, _ := client.GenerateResponse(received.Challenge, method, nil)
+			results[method] = response == expected
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if received.Challenge == "no-verdict" {
+			// Results without valid, e.g. alongside an error
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Error: stringPtr("internal"), Results: results})
+			return
+		}
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true), Results: results})
+	}))
+	defer server.Close()
+	client.baseURL = server.URL
+
+	responsesFor := func(challenge string) map[ResponseMethod]string {
+		hmacResponse, _ := client.GenerateResponse(challenge, ResponseMethodHMAC, nil)
+		hashResponse, _ := client.GenerateResponse(challenge, ResponseMethodHash, nil)
+		return map[ResponseMethod]string{ResponseMethodHMAC: hmacResponse, ResponseMethodHash: hashResponse}
+	}
+
+	tests := []struct {
+		name      string
+		challenge string
+		responses map[ResponseMethod]string
+		valid     bool
+	}{
+		{"all confirmed", "test-challenge", responsesFor("test-challenge"), true},
+		{"one rejected", "test-challenge", map[ResponseMethod]string{
+			ResponseMethodHMAC: responsesFor("test-challenge")[ResponseMethodHMAC],
+			ResponseMethodHash: "wrong",
+		}, false},
+		{"one unconfirmed", "partial", responsesFor("partial"), false},
+		{"no overall verdict", "no-verdict", responsesFor("no-verdict"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.ValidateMulti(tt.challenge, tt.responses)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if result.IsValid() != tt.valid {
+				t.Errorf("Expected valid=%v, got %+v", tt.valid, result)
+			}
+			if received.Challenge != tt.challenge || len(received.Responses) != 2 {
+				t.Errorf("Expected the challenge with both responses to be sent, got %+v", received)
+			}
+		})
+	}
+
+	if _, err := client.ValidateMulti("test-challenge", nil); err == nil {
+		t.Error("Expected error for no responses")
+	}
+}
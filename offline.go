@@ -0,0 +1,70 @@
+package keyclaim
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// ErrNoOfflineVectors is returned by ValidateOffline before LoadOfflineVectors
+// has succeeded
+var ErrNoOfflineVectors = errors.New("keyclaim: no offline vectors loaded")
+
+// ErrUnknownChallenge is returned by ValidateOffline for a challenge the
+// loaded vectors don't cover
+var ErrUnknownChallenge = errors.New("keyclaim: challenge not found in offline vectors")
+
+// LoadOfflineVectors loads precomputed challenge/response pairs from a JSON
+// file for ValidateOffline, replacing any loaded before. The file holds an
+// array of SignedChallenge objects, as produced by marshaling PreSign's
+// result, e.g. [{"challenge": "...", "response": "...", "method": "hmac"}].
+// A challenge may appear more than once, e.g. with one response per method.
+func (c *KeyClaimClient) LoadOfflineVectors(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read offline vectors: %w", err)
+	}
+
+	var signed []SignedChallenge
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return fmt.Errorf("failed to decode offline vectors: %w", err)
+	}
+
+	vectors := make(map[string][]string, len(signed))
+	for i, s := range signed {
+		if s.Response == "" {
+			return fmt.Errorf("failed to decode offline vectors: entry %d has no response", i)
+		}
+		vectors[s.Challenge] = append(vectors[s.Challenge], s.Response)
+	}
+
+	c.offlineMu.Lock()
+	defer c.offlineMu.Unlock()
+	c.offlineVectors = vectors
+	return nil
+}
+
+// ValidateOffline checks response against the vectors loaded with
+// LoadOfflineVectors, without contacting the API, for air-gapped
+// environments that receive verification data out-of-band. A challenge the
+// vectors don't cover yields false and ErrUnknownChallenge.
+func (c *KeyClaimClient) ValidateOffline(challenge, response string) (bool, error) {
+	c.offlineMu.RLock()
+	defer c.offlineMu.RUnlock()
+
+	if c.offlineVectors == nil {
+		return false, ErrNoOfflineVectors
+	}
+	expected, ok := c.offlineVectors[challenge]
+	if !ok {
+		return false, ErrUnknownChallenge
+	}
+
+	match := 0
+	for _, e := range expected {
+		match |= subtle.ConstantTimeCompare([]byte(e), []byte(response))
+	}
+	return match == 1, nil
+}
@@ -0,0 +1,82 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateOffline(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+
+	if _, err := client.ValidateOffline("offline-challenge-1", "anything"); !errors.Is(err, ErrNoOfflineVectors) {
+		t.Fatalf("Expected ErrNoOfflineVectors before loading, got %v", err)
+	}
+
+	if err := client.LoadOfflineVectors(filepath.Join("testdata", "offline_vectors.json")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The fixture was generated with the secret "test-secret"
+	signer, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+	hmacResponse, _ := signer.GenerateResponse("offline-challenge-2", ResponseMethodHMAC, nil)
+	hashResponse, _ := signer.GenerateResponse("offline-challenge-2", ResponseMethodHash, nil)
+
+	tests := []struct {
+		name      string
+		challenge string
+		response  string
+		valid     bool
+		err       error
+	}{
+		{"matching", "offline-challenge-1", "e01e000f53d222d4c39c3e873fb628c9c65a2c40d359df364b64db7c67d2c5a8", true, nil},
+		{"matching hmac", "offline-challenge-2", hmacResponse, true, nil},
+		{"matching hash", "offline-challenge-2", hashResponse, true, nil},
+		{"non-matching", "offline-challenge-1", hmacResponse, false, nil},
+		{"unknown challenge", "other-challenge", hmacResponse, false, ErrUnknownChallenge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			valid, err := client.ValidateOffline(tt.challenge, tt.response)
+			if valid != tt.valid || !errors.Is(err, tt.err) {
+				t.Errorf("Expected (%v, %v), got (%v, %v)", tt.valid, tt.err, valid, err)
+			}
+		})
+	}
+}
+
+func TestLoadOfflineVectors_PreSigned(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	signed, _ := client.PreSign([]string{"c1", "c2"}, ResponseMethodHash)
+	data, _ := json.Marshal(signed)
+	path := filepath.Join(t.TempDir(), "vectors.json")
+	os.WriteFile(path, data, 0o600)
+
+	if err := client.LoadOfflineVectors(path); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if valid, err := client.ValidateOffline("c2", signed[1].Response); !valid || err != nil {
+		t.Errorf("Expected PreSign output to validate offline, got (%v, %v)", valid, err)
+	}
+}
+
+func TestLoadOfflineVectors_Invalid(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	dir := t.TempDir()
+
+	if err := client.LoadOfflineVectors(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+
+	path := filepath.Join(dir, "vectors.json")
+	for _, content := range []string{`{"c1": "r1"}`, `[{"challenge": "c1"}]`} {
+		os.WriteFile(path, []byte(content), 0o600)
+		if err := client.LoadOfflineVectors(path); err == nil {
+			t.Errorf("Expected error for %s", content)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by ChallengePool.Get once the pool is closed
+var ErrPoolClosed = errors.New("keyclaim: challenge pool is closed")
+
+// ChallengePool keeps a buffer of pre-created challenges, refilled by a
+// background goroutine, so the full flow doesn't wait on the create call.
+// Its Source method can be used as Config.ChallengeSource.
+type ChallengePool struct {
+	client     *KeyClaimClient
+	ttl        int
+	challenges chan *CreateChallengeResponse
+
+	cancel    context.CancelFunc
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewChallengePool starts a pool holding up to size challenges created with
+// ttl. The background refill stops when ctx is done or Close is called.
+func NewChallengePool(ctx context.Context, client *KeyClaimClient, size, ttl int) *ChallengePool {
+	if size <= 0 {
+		size = 1
+	}
+	if ttl == 0 {
+		ttl = defaultTTL
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &ChallengePool{
+		client:     client,
+		ttl:        ttl,
+		challenges: make(chan *CreateChallengeResponse, size),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+
+	go p.refill(ctx)
+
+	return p
+}
+
+// Get returns a pooled challenge that hasn't expired yet, by its ExpiresAt,
+// waiting for the refill goroutine if the pool is empty
+func (p *ChallengePool) Get(ctx context.Context) (*CreateChallengeResponse, error) {
+	for {
+		select {
+		case challenge := <-p.challenges:
+			if time.Now().Before(challenge.expiresAt()) {
+				return challenge, nil
+			}
+		case <-p.done:
+			return nil, ErrPoolClosed
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Source returns a pooled challenge like Get, with the signature of
+// Config.ChallengeSource. The ttl is ignored: pooled challenges were created
+// with the pool's TTL.
+func (p *ChallengePool) Source(ctx context.Context, ttl int) (*CreateChallengeResponse, error) {
+	return p.Get(ctx)
+}
+
+// Close stops the refill goroutine, waits for it to exit and discards the
+// remaining challenges. It is safe to call more than once.
+func (p *ChallengePool) Close() error {
+	p.closeOnce.Do(func() {
+		p.cancel()
+		<-p.done
+
+		for {
+			select {
+			case <-p.challenges:
+			default:
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (p *ChallengePool) refill(ctx context.Context) {
+	defer close(p.done)
+
+	backoff := p.client.retryBackoff
+	for {
+		challenge, err := p.client.CreateChallengeContext(ctx, p.ttl)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			continue
+		}
+
+		select {
+		case p.challenges <- challenge:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
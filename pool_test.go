@@ -0,0 +1,143 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newPoolTestClient(t *testing.T) *KeyClaimClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "pooled-challenge", ExpiresIn: 30})
+	}))
+	t.Cleanup(server.Close)
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+	return client
+}
+
+// assertNoRefillGoroutine fails if a pool refill goroutine is still running
+func assertNoRefillGoroutine(t *testing.T) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		buf := make([]byte, 1<<20)
+		stacks := string(buf[:runtime.Stack(buf, true)])
+		if !strings.Contains(stacks, "(*ChallengePool).refill") {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected no refill goroutine to remain, got:\n%s", stacks)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestChallengePool_Get(t *testing.T) {
+	pool := NewChallengePool(context.Background(), newPoolTestClient(t), 2, 30)
+	defer pool.Close()
+
+	challenge, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.Challenge != "pooled-challenge" {
+		t.Errorf("Expected challenge 'pooled-challenge', got %s", challenge.Challenge)
+	}
+}
+
+func TestChallengePool_SkipsExpired(t *testing.T) {
+	expired := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expiresAt := time.Now().Add(30 * time.Second)
+		challenge := "fresh-challenge"
+		if expired {
+			expiresAt = time.Now().Add(-time.Second)
+			challenge = "expired-challenge"
+			expired = false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: challenge, ExpiresIn: 30, ExpiresAt: &expiresAt})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+	pool := NewChallengePool(context.Background(), client, 2, 30)
+	defer pool.Close()
+
+	challenge, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if challenge.Challenge != "fresh-challenge" {
+		t.Errorf("Expected the expired challenge to be skipped, got %s", challenge.Challenge)
+	}
+}
+
+func TestChallengePool_Source(t *testing.T) {
+	pool := NewChallengePool(context.Background(), newPoolTestClient(t), 2, 30)
+	defer pool.Close()
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+		received = append(received, body.Challenge)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:          "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ChallengeSource: pool.Source,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.Validate(ResponseMethodHMAC, 30, nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(received) != 1 || received[0] != "pooled-challenge" {
+		t.Errorf("Expected the pooled challenge to be validated, got %v", received)
+	}
+}
+
+func TestChallengePool_Close(t *testing.T) {
+	pool := NewChallengePool(context.Background(), newPoolTestClient(t), 2, 30)
+	pool.Get(context.Background())
+
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatalf("Expected second Close to be a no-op, got %v", err)
+	}
+	assertNoRefillGoroutine(t)
+
+	if len(pool.challenges) != 0 {
+		t.Errorf("Expected pool to be drained, got %d challenges", len(pool.challenges))
+	}
+	if _, err := pool.Get(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed, got %v", err)
+	}
+}
+
+func TestChallengePool_ContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewChallengePool(ctx, newPoolTestClient(t), 2, 30)
+	pool.Get(context.Background())
+
+	cancel()
+	assertNoRefillGoroutine(t)
+	pool.Close()
+}
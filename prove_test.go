@@ -0,0 +1,101 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newProveServer stubs the combined proof endpoint, verifying proofs with
+// secret against the nonce embedded in the bearer key, each accepted once
+func newProveServer(t *testing.T, secret string) *httptest.Server {
+	var mu sync.Mutex
+	used := make(map[string]bool)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/challenge/prove" {
+			t.Errorf("Expected path /api/challenge/prove, got %s", r.URL.Path)
+		}
+
+		var proof ProveRequest
+		json.NewDecoder(r.Body).Decode(&proof)
+
+		apiKey := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		issued := apiKey[strings.LastIndexByte(apiKey, '.')+1:]
+
+		h := hmac.New(sha256.New, []byte(secret))
+		h.Write([]byte(issued))
+
+		mu.Lock()
+		reused := used[issued]
+		used[issued] = true
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if proof.Nonce != issued || reused || proof.Response != hex.EncodeToString(h.Sum(nil)) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(false), Error: stringPtr("Invalid proof")})
+			return
+		}
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+}
+
+func TestProveOnce(t *testing.T) {
+	server := newProveServer(t, "test-secret")
+	defer server.Close()
+
+	client, _ := NewClientWithSecret("kc_test1234567890123456789012345678901234567890.nonce-1", "test-secret")
+	client.baseURL = server.URL
+
+	result, err := client.ProveOnce()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected proof to be valid")
+	}
+
+	result, err = client.ProveOnce()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.IsValid() {
+		t.Error("Expected a reused nonce to be rejected")
+	}
+
+	client.SetAPIKey("kc_test1234567890123456789012345678901234567890.nonce-2")
+	if result, err := client.ProveOnce(); err != nil || !result.IsValid() {
+		t.Errorf("Expected a proof with a fresh nonce to be valid, got (%+v, %v)", result, err)
+	}
+}
+
+func TestProveOnce_WrongSecret(t *testing.T) {
+	server := newProveServer(t, "test-secret")
+	defer server.Close()
+
+	client, _ := NewClientWithSecret("kc_test1234567890123456789012345678901234567890.nonce-1", "wrong-secret")
+	client.baseURL = server.URL
+
+	result, err := client.ProveOnce()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.IsValid() {
+		t.Error("Expected proof with the wrong secret to be invalid")
+	}
+}
+
+func TestProveOnce_NoNonce(t *testing.T) {
+	client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	if _, err := client.ProveOnce(); !errors.Is(err, ErrNoProofNonce) {
+		t.Errorf("Expected ErrNoProofNonce, got %v", err)
+	}
+}
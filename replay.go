@@ -0,0 +1,113 @@
+package keyclaim
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrReplayDetected is matched by errors.Is for API errors reporting that a
+// response was already used, with a "replay_detected" or "already_used" code.
+// With Config.RejectReplays, it is also returned without contacting the API
+// for a challenge and response already submitted within Config.ReplayWindow.
+var ErrReplayDetected = errors.New("keyclaim: response already used")
+
+// maxTrackedSubmissions bounds the memory used by the replay tracker; the
+// oldest submissions are forgotten first
+const maxTrackedSubmissions = 4096
+
+// isReplayCode reports whether an API error code denotes a replayed response
+func isReplayCode(code string) bool {
+	return strings.EqualFold(code, "replay_detected") || strings.EqualFold(code, "already_used")
+}
+
+type submission struct {
+	key  [sha256.Size]byte
+	sent time.Time
+}
+
+// replayTracker remembers challenge/response pairs the server has answered,
+// keyed on their SHA-256, to flag resubmissions before they are sent. Pairs
+// that never got an answer, e.g. after a transport error or a 5xx, aren't
+// remembered, so they can be retried. The window is the same for every
+// entry, so entries expire in the order they were recorded.
+type replayTracker struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu          sync.Mutex
+	order       *list.List // Oldest submission at the front
+	submissions map[[sha256.Size]byte]*list.Element
+}
+
+func newReplayTracker(window time.Duration) *replayTracker {
+	if window <= 0 {
+		return nil
+	}
+	return &replayTracker{
+		window:      window,
+		now:         time.Now,
+		order:       list.New(),
+		submissions: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// seen reports whether response to challenge was answered within the window
+func (t *replayTracker) seen(challenge, response string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(t.now())
+	_, ok := t.submissions[submissionKey(challenge, response)]
+	return ok
+}
+
+// record remembers that the server answered response to challenge
+func (t *replayTracker) record(challenge, response string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.prune(now)
+	key := submissionKey(challenge, response)
+	if _, ok := t.submissions[key]; !ok {
+		t.submissions[key] = t.order.PushBack(&submission{key: key, sent: now})
+	}
+}
+
+// prune forgets the submissions older than the window, and the oldest ones
+// beyond maxTrackedSubmissions
+func (t *replayTracker) prune(now time.Time) {
+	for front := t.order.Front(); front != nil; front = t.order.Front() {
+		s := front.Value.(*submission)
+		if now.Sub(s.sent) < t.window && t.order.Len() < maxTrackedSubmissions {
+			break
+		}
+		t.order.Remove(front)
+		delete(t.submissions, s.key)
+	}
+}
+
+func submissionKey(challenge, response string) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(challenge))
+	h.Write([]byte{0})
+	h.Write([]byte(response))
+	var key [sha256.Size]byte
+	h.Sum(key[:0])
+	return key
+}
+
+// isAnswered reports whether a validation outcome is an answer from the
+// server, as opposed to a transport error or a 5xx that may be retried
+func isAnswered(result *ValidateChallengeResponse, err error) bool {
+	if err == nil {
+		return result != nil
+	}
+	var keyclaimErr *KeyClaimError
+	return errors.As(err, &keyclaimErr) && keyclaimErr.StatusCode < http.StatusInternalServerError
+}
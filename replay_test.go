@@ -0,0 +1,170 @@
+package keyclaim
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayDetected_ServerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body ValidateChallengeOptions
+		json.NewDecoder(r.Body).Decode(&body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if body.Challenge == "error-body" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"replay_detected","message":"Response already used"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"valid":false,"error":"already_used"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ErrorOnInvalid: true,
+	})
+	client.baseURL = server.URL
+
+	_, err := client.ValidateChallenge("error-body", "test-response", nil)
+	var keyclaimErr *KeyClaimError
+	if !errors.Is(err, ErrReplayDetected) || !errors.As(err, &keyclaimErr) || keyclaimErr.StatusCode != http.StatusConflict {
+		t.Errorf("Expected a KeyClaimError matching ErrReplayDetected, got %v", err)
+	}
+
+	if _, err := client.ValidateChallenge("invalid-result", "test-response", nil); !errors.Is(err, ErrReplayDetected) || !errors.Is(err, ErrValidationFailed) {
+		t.Errorf("Expected an invalid result matching ErrReplayDetected, got %v", err)
+	}
+}
+
+func TestReplayWindow(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	t.Run("warn", func(t *testing.T) {
+		requests = 0
+		logger := &fakeLogger{}
+		events := make(chan Event, 10)
+		client, _ := NewClientWithConfig(Config{
+			APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+			Secret:       "test-secret",
+			Logger:       logger,
+			Events:       events,
+			ReplayWindow: time.Minute,
+		})
+		client.baseURL = server.URL
+
+		client.ValidateChallenge("test-challenge", "response-1", nil)
+		client.ValidateChallenge("test-challenge", "response-2", nil)
+		if warnings := countWarnings(logger, "already submitted"); warnings != 0 {
+			t.Fatalf("Expected no warning for distinct pairs, got %v", logger.records)
+		}
+
+		if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); err != nil {
+			t.Fatalf("Expected the resubmission to be sent, got %v", err)
+		}
+		if requests != 3 {
+			t.Errorf("Expected 3 requests, got %d", requests)
+		}
+		if warnings := countWarnings(logger, "already submitted"); warnings != 1 {
+			t.Errorf("Expected 1 warning, got %v", logger.records)
+		}
+
+		suspected := 0
+		for len(events) > 0 {
+			if event := <-events; event.Type == EventReplaySuspected && event.Challenge == "test-challenge" {
+				suspected++
+			}
+		}
+		if suspected != 1 {
+			t.Errorf("Expected 1 EventReplaySuspected, got %d", suspected)
+		}
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		requests = 0
+		client, _ := NewClientWithConfig(Config{
+			APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+			ReplayWindow:  time.Minute,
+			RejectReplays: true,
+		})
+		client.baseURL = server.URL
+		now := time.Now()
+		client.replays.now = func() time.Time { return now }
+
+		client.ValidateChallenge("test-challenge", "response-1", nil)
+		if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); !errors.Is(err, ErrReplayDetected) {
+			t.Fatalf("Expected ErrReplayDetected, got %v", err)
+		}
+		if requests != 1 {
+			t.Errorf("Expected the replay not to be sent, got %d requests", requests)
+		}
+
+		now = now.Add(time.Minute)
+		if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); err != nil {
+			t.Errorf("Expected the pair to be forgotten after the window, got %v", err)
+		}
+	})
+}
+
+func TestReplayWindow_RetryAfterServerError(t *testing.T) {
+	failing := true
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error":"unavailable"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:        "kc_test123456789012345678901234567890123456789012345678901234567890",
+		ReplayWindow:  time.Minute,
+		RejectReplays: true,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); err == nil || errors.Is(err, ErrReplayDetected) {
+		t.Fatalf("Expected a server error, got %v", err)
+	}
+
+	failing = false
+	if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); err != nil {
+		t.Fatalf("Expected the retry after a 5xx to be sent, got %v", err)
+	}
+	if _, err := client.ValidateChallenge("test-challenge", "response-1", nil); !errors.Is(err, ErrReplayDetected) {
+		t.Errorf("Expected ErrReplayDetected once the server answered, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+}
+
+func countWarnings(logger *fakeLogger, substring string) int {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+
+	count := 0
+	for _, record := range logger.records {
+		if strings.HasPrefix(record, "WARN ") && strings.Contains(record, substring) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,37 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// signRequest sets the X-Timestamp and X-Signature headers on req.
+//
+// The signature is the hex-encoded HMAC-SHA256, keyed with the secret (as
+// derived by Config.KeyDeriver when set), of:
+//
+//	METHOD + "\n" + PATH + "\n" + TIMESTAMP + "\n" + hex(SHA-256(body))
+//
+// where PATH is followed by "?" and the raw query for requests with one, so
+// query parameters can't be changed without breaking the signature,
+// TIMESTAMP is the Unix time in seconds, as sent in X-Timestamp, and body is
+// empty for requests without one.
+func (c *KeyClaimClient) signRequest(req *http.Request, body []byte, now time.Time) {
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	bodyHash := sha256.Sum256(body)
+
+	path := req.URL.Path
+	if req.URL.RawQuery != "" {
+		path += "?" + req.URL.RawQuery
+	}
+
+	h := hmac.New(sha256.New, c.key)
+	h.Write([]byte(req.Method + "\n" + path + "\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Signature", hex.EncodeToString(h.Sum(nil)))
+}
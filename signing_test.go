@@ -0,0 +1,89 @@
+package keyclaim
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSignRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timestamp := r.Header.Get("X-Timestamp")
+		signature := r.Header.Get("X-Signature")
+		if timestamp == "" || signature == "" {
+			t.Errorf("Expected X-Timestamp and X-Signature headers, got %q and %q", timestamp, signature)
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		bodyHash := sha256.Sum256(body)
+		h := hmac.New(sha256.New, []byte("test-secret"))
+		h.Write([]byte("POST\n/api/challenge/create\n" + timestamp + "\n" + hex.EncodeToString(bodyHash[:])))
+		if expected := hex.EncodeToString(h.Sum(nil)); signature != expected {
+			t.Errorf("Expected signature %s, got %s", expected, signature)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret:       "test-secret",
+		SignRequests: true,
+	})
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
+
+func TestSignRequests_Query(t *testing.T) {
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Secret:       "test-secret",
+		SignRequests: true,
+	})
+	now := time.Unix(1700000000, 0)
+
+	signature := func(url string) string {
+		req, _ := http.NewRequest("GET", url, nil)
+		client.signRequest(req, nil, now)
+		return req.Header.Get("X-Signature")
+	}
+
+	bodyHash := sha256.Sum256(nil)
+	h := hmac.New(sha256.New, []byte("test-secret"))
+	h.Write([]byte("GET\n/api/challenge/list?cursor=abc\n1700000000\n" + hex.EncodeToString(bodyHash[:])))
+	if expected := hex.EncodeToString(h.Sum(nil)); signature("https://api.example.com/api/challenge/list?cursor=abc") != expected {
+		t.Errorf("Expected the query in the canonical string")
+	}
+	if signature("https://api.example.com/api/challenge/list?cursor=abc") == signature("https://api.example.com/api/challenge/list?cursor=xyz") {
+		t.Error("Expected a changed query to change the signature")
+	}
+}
+
+func TestSignRequests_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Signature") != "" {
+			t.Error("Expected no X-Signature header when signing is disabled")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	if _, err := client.CreateChallenge(30); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// StreamChallenges opens a long-lived connection to the streaming create
+// endpoint and delivers challenges as the server sends them, one JSON object
+// per line. Both channels are closed when the stream ends. The error channel
+// receives at most one error: the failure that ended the stream, or ctx's
+// error if ctx was cancelled. A stream closed cleanly by the server ends
+// without an error.
+//
+// Opening the stream goes through the same path as other requests, with
+// retries, the circuit breaker, the concurrency limit and the audit and debug
+// hooks, but the open stream isn't subject to the client's timeout and
+// doesn't hold a concurrency slot; use ctx to bound its lifetime. The debug
+// hook and request history see the stream's response without its body.
+func (c *KeyClaimClient) StreamChallenges(ctx context.Context, ttl int) (<-chan CreateChallengeResponse, <-chan error) {
+	challenges := make(chan CreateChallengeResponse)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(challenges)
+		defer close(errs)
+
+		if err := c.streamChallenges(ctx, ttl, challenges); err != nil {
+			errs <- err
+		}
+	}()
+
+	return challenges, errs
+}
+
+func (c *KeyClaimClient) streamChallenges(ctx context.Context, ttl int, challenges chan<- CreateChallengeResponse) error {
+	ttl, err := c.resolveTTL(ttl)
+	if err != nil {
+		return err
+	}
+
+	reqBody := map[string]interface{}{
+		"ttl": c.ttlValue(time.Duration(ttl) * time.Second),
+	}
+
+	req, err := c.newRequest(ctx, "POST", "/api/challenge/stream", reqBody, false)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+
+	resp, err := c.send(req, nil, true)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to open challenge stream: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.handleErrorResponse(resp, "Failed to open challenge stream")
+	}
+	// The Date header dates the stream's opening, so that's when to compare it
+	skew := serverClockSkew(resp, time.Now())
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to decode streamed challenge: %w", err)
+		}
+
+		var challenge CreateChallengeResponse
+		if err := json.Unmarshal(renameFields(line, c.responseFieldMap), &challenge); err != nil {
+			return fmt.Errorf("failed to decode streamed challenge: %w", err)
+		}
+		challenge.received(time.Now(), skew)
+
+		select {
+		case challenges <- challenge:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package keyclaim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamChallenges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/challenge/stream" {
+			t.Errorf("Expected path /api/challenge/stream, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		for i := 1; i <= 3; i++ {
+			fmt.Fprintf(w, "{\"challenge\":\"streamed-%d\",\"expires_in\":30}\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	challenges, errs := client.StreamChallenges(context.Background(), 30)
+
+	var received []string
+	for challenge := range challenges {
+		received = append(received, challenge.Challenge)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(received) != 3 || received[0] != "streamed-1" || received[2] != "streamed-3" {
+		t.Errorf("Expected 3 streamed challenges in order, got %v", received)
+	}
+}
+
+func TestStreamChallenges_ReceivedAt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"challenge":"streamed-1","expires_in":30}`)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	before := time.Now()
+	challenges, errs := client.StreamChallenges(context.Background(), 30)
+
+	challenge, ok := <-challenges
+	if !ok {
+		t.Fatalf("Expected a challenge, got %v", <-errs)
+	}
+	for range challenges {
+	}
+
+	if challenge.ReceivedAt == nil || challenge.ReceivedAt.Before(before) {
+		t.Errorf("Expected ReceivedAt to be set on receipt, got %v", challenge.ReceivedAt)
+	}
+	if challenge.ExpiresAt == nil || !challenge.ExpiresAt.Equal(challenge.ReceivedAt.Add(30*time.Second)) {
+		t.Errorf("Expected ExpiresAt 30s after receipt, got %v", challenge.ExpiresAt)
+	}
+	if challenge.AgeExceeds(time.Minute) {
+		t.Error("Expected a fresh streamed challenge not to exceed 1m")
+	}
+}
+
+func TestStreamChallenges_Hooks(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprintln(w, `{"challenge":"streamed-1","expires_in":30}`)
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+
+	audited := make(chan AuditRecord, 1)
+	exchanges := make(chan DebugExchange, 1)
+	client, _ := NewClientWithConfig(Config{
+		APIKey:    "kc_test123456789012345678901234567890123456789012345678901234567890",
+		AuditHook: func(record AuditRecord) { audited <- record },
+		DebugHook: func(exchange DebugExchange) { exchanges <- exchange },
+	})
+	client.baseURL = server.URL
+
+	challenges, errs := client.StreamChallenges(context.Background(), 30)
+
+	// The first challenge arrives while the stream is still open, so the
+	// debug hook mustn't have buffered the body
+	if challenge := <-challenges; challenge.Challenge != "streamed-1" {
+		t.Errorf("Expected streamed-1, got %s", challenge.Challenge)
+	}
+	if record := <-audited; record.Path != "/api/challenge/stream" {
+		t.Errorf("Expected the stream request to be audited, got %+v", record)
+	}
+	if exchange := <-exchanges; exchange.StatusCode != http.StatusOK || exchange.ResponseBody != nil {
+		t.Errorf("Expected the exchange without the stream body, got %+v", exchange)
+	}
+
+	close(release)
+	for range challenges {
+	}
+	if err := <-errs; err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestStreamChallenges_CircuitOpen(t *testing.T) {
+	client, _ := NewClientWithConfig(Config{
+		APIKey:         "kc_test123456789012345678901234567890123456789012345678901234567890",
+		CircuitBreaker: &CircuitBreakerConfig{},
+	})
+	client.breaker.open = true
+	client.breaker.openedAt = time.Now()
+
+	challenges, errs := client.StreamChallenges(context.Background(), 30)
+	for range challenges {
+	}
+	if err := <-errs; !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestStreamChallenges_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		fmt.Fprint(w, "{\"challenge\":\"streamed-1\",\"expires_in\":30}\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	challenges, errs := client.StreamChallenges(ctx, 30)
+
+	if challenge := <-challenges; challenge.Challenge != "streamed-1" {
+		t.Fatalf("Expected challenge 'streamed-1', got %q", challenge.Challenge)
+	}
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the stream to stop after cancellation")
+	}
+	if _, ok := <-challenges; ok {
+		t.Error("Expected the challenge channel to be closed")
+	}
+}
+
+func TestStreamChallenges_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"not_found"}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	challenges, errs := client.StreamChallenges(context.Background(), 30)
+	if _, ok := <-challenges; ok {
+		t.Error("Expected no challenges")
+	}
+
+	var keyClaimErr *KeyClaimError
+	if err := <-errs; !errors.As(err, &keyClaimErr) || keyClaimErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 KeyClaimError, got %v", err)
+	}
+}
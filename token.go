@@ -0,0 +1,108 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidToken is returned by ValidateToken for tokens that don't decode
+var ErrInvalidToken = errors.New("keyclaim: invalid token")
+
+const tokenVersion = 1
+
+// tokenPayload is the JSON encoded in a token, with short field names to
+// keep tokens compact
+type tokenPayload struct {
+	Version            int            `json:"v"`
+	Challenge          string         `json:"c"`
+	Response           string         `json:"r"`
+	Method             ResponseMethod `json:"m"`
+	ExpiresAt          int64          `json:"e"` // Unix seconds
+	ChallengeID        string         `json:"i,omitempty"`
+	DecryptedChallenge *string        `json:"d,omitempty"`
+	CustomData         interface{}    `json:"x,omitempty"` // Only with Config.SendCustomData
+}
+
+// IssueToken creates a challenge, generates its response and encodes both,
+// with the method and expiry, into a compact URL-safe token, for handing off
+// to another service that validates it with ValidateToken. Like a response,
+// a token proves possession of the secret: anyone holding it can submit it,
+// so treat it as a credential until it expires. The custom method uses
+// Config.DefaultCustomData.
+func (c *KeyClaimClient) IssueToken(method ResponseMethod, ttl int) (string, error) {
+	return c.IssueTokenContext(context.Background(), method, ttl)
+}
+
+// IssueTokenContext issues a token like IssueToken, honoring ctx cancellation
+func (c *KeyClaimClient) IssueTokenContext(ctx context.Context, method ResponseMethod, ttl int) (string, error) {
+	challenge, err := c.obtainChallenge(ctx, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	opts, err := c.respond(ctx, challenge, method, nil)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(tokenPayload{
+		Version:            tokenVersion,
+		Challenge:          opts.Challenge,
+		Response:           opts.Response,
+		Method:             method,
+		ExpiresAt:          challenge.expiresAt().Unix(),
+		ChallengeID:        opts.ChallengeID,
+		DecryptedChallenge: opts.DecryptedChallenge,
+		CustomData:         opts.CustomData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// ValidateToken decodes a token from IssueToken and validates the challenge
+// and response it carries. Expired tokens fail with ErrChallengeExpired
+// without contacting the API, and malformed ones with ErrInvalidToken.
+func (c *KeyClaimClient) ValidateToken(token string) (*ValidateChallengeResponse, error) {
+	return c.ValidateTokenContext(context.Background(), token)
+}
+
+// ValidateTokenContext validates a token like ValidateToken, honoring ctx
+// cancellation
+func (c *KeyClaimClient) ValidateTokenContext(ctx context.Context, token string) (*ValidateChallengeResponse, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var payload tokenPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if payload.Version != tokenVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidToken, payload.Version)
+	}
+	if payload.Challenge == "" || payload.Response == "" {
+		return nil, fmt.Errorf("%w: missing challenge or response", ErrInvalidToken)
+	}
+
+	expiresAt := time.Unix(payload.ExpiresAt, 0)
+	if !time.Now().Before(expiresAt) {
+		return nil, ErrChallengeExpired
+	}
+	ctx, cancel := context.WithDeadline(ctx, expiresAt)
+	defer cancel()
+
+	return c.ValidateChallengeWithOptions(ctx, ValidateChallengeOptions{
+		Challenge:          payload.Challenge,
+		Response:           payload.Response,
+		DecryptedChallenge: payload.DecryptedChallenge,
+		ChallengeID:        payload.ChallengeID,
+		CustomData:         payload.CustomData,
+	})
+}
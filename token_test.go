@@ -0,0 +1,111 @@
+package keyclaim
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIssueToken_RoundTrip(t *testing.T) {
+	signer, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", "test-secret")
+
+	var received ValidateChallengeOptions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/api/challenge/create":
+			json.NewEncoder(w).Encode(CreateChallengeResponse{
+				Challenge:   "test-challenge-123",
+				ChallengeID: "ch_1",
+				ExpiresIn:   30,
+			})
+		case "/api/challenge/validate":
+			json.NewDecoder(r.Body).Decode(&received)
+			expected, _ := signer.GenerateResponse(received.Challenge, ResponseMethodHMAC, nil)
+			json.NewEncoder(w).Encode(ValidateChallengeResponse{
+				Valid: boolPtr(received.Response == expected),
+			})
+		}
+	}))
+	defer server.Close()
+	signer.baseURL = server.URL
+
+	token, err := signer.IssueToken(ResponseMethodHMAC, 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// The validating service holds only an API key, not the secret
+	validator, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	validator.baseURL = server.URL
+
+	result, err := validator.ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.IsValid() {
+		t.Error("Expected the token to validate")
+	}
+	if received.Challenge != "test-challenge-123" || received.ChallengeID != "ch_1" {
+		t.Errorf("Expected the token's challenge and id to be sent, got %+v", received)
+	}
+}
+
+func TestValidateToken_Expired(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ValidateChallengeResponse{Valid: boolPtr(true)})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	data, _ := json.Marshal(tokenPayload{
+		Version:   tokenVersion,
+		Challenge: "test-challenge",
+		Response:  "test-response",
+		Method:    ResponseMethodHMAC,
+		ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+	})
+	if _, err := client.ValidateToken(base64.RawURLEncoding.EncodeToString(data)); !errors.Is(err, ErrChallengeExpired) {
+		t.Errorf("Expected ErrChallengeExpired, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no request for an expired token, got %d", requests)
+	}
+}
+
+func TestValidateToken_Invalid(t *testing.T) {
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	expiresAt := time.Now().Add(time.Minute).Unix()
+	encode := func(p tokenPayload) string {
+		data, _ := json.Marshal(p)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"not base64", "not a token!"},
+		{"not json", base64.RawURLEncoding.EncodeToString([]byte("plain text"))},
+		{"unknown version", encode(tokenPayload{Version: 2, Challenge: "c", Response: "r", ExpiresAt: expiresAt})},
+		{"no response", encode(tokenPayload{Version: tokenVersion, Challenge: "c", ExpiresAt: expiresAt})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := client.ValidateToken(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package keyclaim
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// TraceTimings breaks down the latency of the last request attempt, reported
+// on ResponseMeta when Config.TraceTimings is set. Phases that didn't happen,
+// such as DNS for an IP address or connecting on a reused connection, are
+// zero.
+type TraceTimings struct {
+	DNS             time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration // From the start of the attempt
+	ReusedConn      bool
+}
+
+// requestTrace collects TraceTimings through an httptrace.ClientTrace. The
+// transport may call the hooks from other goroutines, e.g. for a dial that
+// lost a happy-eyeballs race, so access is guarded.
+type requestTrace struct {
+	mu      sync.Mutex
+	start   time.Time
+	dns     time.Time
+	connect time.Time
+	tls     time.Time
+	timings TraceTimings
+}
+
+// withTrace returns ctx with a ClientTrace recording into a new requestTrace
+func withTrace(ctx context.Context) (context.Context, *requestTrace) {
+	t := &requestTrace{start: time.Now()}
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { t.mark(&t.dns) },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.since(&t.dns, &t.timings.DNS)
+		},
+		ConnectStart: func(string, string) { t.mark(&t.connect) },
+		ConnectDone: func(string, string, error) {
+			t.since(&t.connect, &t.timings.Connect)
+		},
+		TLSHandshakeStart: func() { t.mark(&t.tls) },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.since(&t.tls, &t.timings.TLSHandshake)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timings.ReusedConn = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.since(&t.start, &t.timings.TimeToFirstByte)
+		},
+	}), t
+}
+
+func (t *requestTrace) mark(at *time.Time) {
+	t.mu.Lock()
+	*at = time.Now()
+	t.mu.Unlock()
+}
+
+func (t *requestTrace) since(start *time.Time, d *time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !start.IsZero() {
+		*d = time.Since(*start)
+	}
+}
+
+// result returns a copy of the timings recorded so far
+func (t *requestTrace) result() *TraceTimings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	timings := t.timings
+	return &timings
+}
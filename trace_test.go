@@ -0,0 +1,67 @@
+package keyclaim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClientWithConfig(Config{
+		APIKey:       "kc_test123456789012345678901234567890123456789012345678901234567890",
+		BaseURL:      server.URL,
+		TraceTimings: true,
+	})
+	client.client.Transport = server.Client().Transport
+
+	_, meta, err := client.CreateChallengeWithMeta(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	trace := meta.Trace
+	if trace == nil {
+		t.Fatal("Expected trace timings")
+	}
+	if trace.DNS < 0 || trace.Connect <= 0 || trace.TLSHandshake <= 0 || trace.TimeToFirstByte <= 0 {
+		t.Errorf("Expected populated, non-negative timings, got %+v", trace)
+	}
+	if trace.ReusedConn {
+		t.Error("Expected a new connection for the first request")
+	}
+
+	// The second request reuses the connection, skipping connect and TLS
+	_, meta, err = client.CreateChallengeWithMeta(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if trace := meta.Trace; !trace.ReusedConn || trace.Connect != 0 || trace.TLSHandshake != 0 || trace.TimeToFirstByte <= 0 {
+		t.Errorf("Expected timings of a reused connection, got %+v", trace)
+	}
+}
+
+func TestTraceTimings_Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CreateChallengeResponse{Challenge: "test-challenge-123", ExpiresIn: 30})
+	}))
+	defer server.Close()
+
+	client, _ := NewClient("kc_test123456789012345678901234567890123456789012345678901234567890")
+	client.baseURL = server.URL
+
+	_, meta, err := client.CreateChallengeWithMeta(context.Background(), 30)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if meta.Trace != nil {
+		t.Errorf("Expected no trace timings by default, got %+v", meta.Trace)
+	}
+}
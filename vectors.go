@@ -0,0 +1,101 @@
+package keyclaim
+
+import "fmt"
+
+// TestVector is a known input/output pair for a response method, shared with
+// the other KeyClaim SDKs to check that they compute identical responses
+type TestVector struct {
+	Challenge  string
+	Secret     string
+	Method     ResponseMethod
+	CustomData interface{} // Only used by ResponseMethodCustom
+	Expected   string
+}
+
+// TestVectors are the cross-SDK test vectors for the hmac, hash and custom
+// methods. Any change to how responses are computed must keep these passing,
+// or it breaks compatibility with servers and the other SDKs.
+var TestVectors = []TestVector{
+	{
+		Challenge: "test-challenge",
+		Secret:    "test-secret",
+		Method:    ResponseMethodHMAC,
+		Expected:  "115eb4bf845b903e1890768543e41526d9808bb1711e07a2f1bad457f8998b42",
+	},
+	{
+		Challenge: "a1b2c3d4e5f6",
+		Secret:    "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Method:    ResponseMethodHMAC,
+		Expected:  "9209f1c8dcd5ba9978a754a9219a083d4f922fe4211cba8406460e4f91f2d046",
+	},
+	{
+		Challenge: "",
+		Secret:    "test-secret",
+		Method:    ResponseMethodHMAC,
+		Expected:  "a41bc6d81d6413576ae0994995e0ad89a416ec97389515c3604f47722122eeeb",
+	},
+	{
+		Challenge: "héllo wörld",
+		Secret:    "sécret",
+		Method:    ResponseMethodHMAC,
+		Expected:  "dacbf3d9e22edf9f38c78f706a10f95883ddb0f8e2af3e9407555000555cccb0",
+	},
+	{
+		Challenge: "test-challenge",
+		Secret:    "test-secret",
+		Method:    ResponseMethodHash,
+		Expected:  "34ed52862ac09a22cccbb48b125e0674e33900fbca6b1f4d809a21df27be0496",
+	},
+	{
+		Challenge: "a1b2c3d4e5f6",
+		Secret:    "kc_test123456789012345678901234567890123456789012345678901234567890",
+		Method:    ResponseMethodHash,
+		Expected:  "8f5ba95b26764a98e9cb53b3fad0f02776219ee35d58e63cb51efaab7b8a884b",
+	},
+	{
+		Challenge: "",
+		Secret:    "test-secret",
+		Method:    ResponseMethodHash,
+		Expected:  "9caf06bb4436cdbfa20af9121a626bc1093c4f54b31c0fa937957856135345b6",
+	},
+	{
+		Challenge: "héllo wörld",
+		Secret:    "sécret",
+		Method:    ResponseMethodHash,
+		Expected:  "1eba7108ce1fe267b96f2b7c9b3e8aee225689de948e71d7801393a18751cfc6",
+	},
+	{
+		Challenge:  "test-challenge",
+		Secret:     "test-secret",
+		Method:     ResponseMethodCustom,
+		CustomData: "custom-string",
+		Expected:   "49b3888d279dd435d7cafaacc81851c6ed224052e898a6e66435534e666c0bc7",
+	},
+	{
+		Challenge:  "test-challenge",
+		Secret:     "test-secret",
+		Method:     ResponseMethodCustom,
+		CustomData: map[string]interface{}{"user_id": 123},
+		Expected:   "749d93705c38a40f0429c0653473c40e5f317d4e6c05a789a54d03a669eb4c6e",
+	},
+}
+
+// VerifyTestVectors checks that GenerateResponse reproduces every entry of
+// TestVectors, returning an error describing the first mismatch
+func VerifyTestVectors() error {
+	for i, vector := range TestVectors {
+		client, err := NewClientWithSecret("kc_test_vectors", vector.Secret)
+		if err != nil {
+			return err
+		}
+
+		response, err := client.GenerateResponse(vector.Challenge, vector.Method, vector.CustomData)
+		if err != nil {
+			return fmt.Errorf("test vector %d (%s): %w", i, vector.Method, err)
+		}
+		if response != vector.Expected {
+			return fmt.Errorf("test vector %d (%s): expected %s, got %s", i, vector.Method, vector.Expected, response)
+		}
+	}
+	return nil
+}
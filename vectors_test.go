@@ -0,0 +1,26 @@
+package keyclaim
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTestVectors(t *testing.T) {
+	for i, vector := range TestVectors {
+		t.Run(fmt.Sprintf("%s/%d", vector.Method, i), func(t *testing.T) {
+			client, _ := NewClientWithSecret("kc_test123456789012345678901234567890123456789012345678901234567890", vector.Secret)
+
+			response, err := client.GenerateResponse(vector.Challenge, vector.Method, vector.CustomData)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if response != vector.Expected {
+				t.Errorf("Expected %s, got %s", vector.Expected, response)
+			}
+		})
+	}
+
+	if err := VerifyTestVectors(); err != nil {
+		t.Errorf("Expected VerifyTestVectors to pass, got %v", err)
+	}
+}